@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"goroutines/internal/fixtures"
+	"goroutines/internal/product/request"
+	"goroutines/internal/product/response"
+	"goroutines/internal/product/seed"
+	"goroutines/pkg/loadgen"
+	"goroutines/router/middleware"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// benchResult is the JSON document written by the bench subcommand
+type benchResult struct {
+	URL      string            `json:"url"`
+	Scale    float64           `json:"scale"`
+	Requests int               `json:"requests_per_iteration"`
+	Think    string            `json:"think"`
+	Reports  []*loadgen.Report `json:"reports"`
+}
+
+// runBench runs the bench subcommand and returns the process exit code
+func runBench(args []string) int {
+	var (
+		baseURL    string
+		strategies string
+		scale      float64
+		jsonPath   string
+		mdPath     string
+		seedValue  uint64
+		adminToken string
+	)
+	opts := loadgen.Options{}
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.StringVar(&baseURL, "url", "http://localhost:8080", "base URL of a running server")
+	fs.StringVar(&strategies, "strategies", "", "comma separated create strategies, every strategy of the server by default")
+	fs.Float64Var(&scale, "scale", 1, "multiplies the duration of every stage, e.g. 0.1 for a quick run")
+	fs.IntVar(&opts.Requests, "requests", 10, "create requests per virtual user iteration")
+	fs.DurationVar(&opts.Think, "think", time.Second, "pause of a virtual user between two iterations")
+	fs.StringVar(&jsonPath, "json", "bench.json", "JSON results file, - for stdout")
+	fs.StringVar(&mdPath, "md", "", "Markdown comparison table file, stdout when empty")
+	fs.Uint64Var(&seedValue, "seed", 1, "seed of the generated products")
+	fs.StringVar(&adminToken, "admin-token", os.Getenv("ADMIN_TOKEN"), "admin token listing the server strategies, $ADMIN_TOKEN by default")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts.Stages = loadgen.Scale(loadgen.K6Stages, scale)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: 2048,
+		},
+	}
+
+	names := splitList(strategies)
+	if len(names) == 0 {
+		var err error
+		if names, err = serverStrategies(ctx, client, baseURL, adminToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to list strategies: %v\n", err)
+			return 1
+		}
+	}
+
+	result := benchResult{
+		URL:      baseURL,
+		Scale:    scale,
+		Requests: opts.Requests,
+		Think:    opts.Think.String(),
+	}
+	gen := seed.NewGenerator(seedValue, fixtures.DefaultCategories)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "Running %s...\n", name)
+
+		var n atomic.Int64
+		report := loadgen.Run(ctx, opts, func(ctx context.Context) error {
+			return createProduct(ctx, client, baseURL, name, gen, int(n.Add(1)))
+		})
+		report.Name = name
+		result.Reports = append(result.Reports, report)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err := writeBenchJSON(jsonPath, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write %s: %v\n", jsonPath, err)
+		return 1
+	}
+	if err := writeBenchMarkdown(mdPath, result.Reports); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write %s: %v\n", mdPath, err)
+		return 1
+	}
+
+	return 0
+}
+
+func createProduct(ctx context.Context, client *http.Client, baseURL, strategy string, gen *seed.Generator, i int) error {
+	p := gen.Product(i)
+	stock := p.Stock
+	body, err := json.Marshal(request.ProductCreateRequest{
+		Name:     p.Name,
+		Sku:      p.Sku,
+		Category: p.Category,
+		ImageUrl: p.ImageUrl,
+		Notes:    p.Notes,
+		Price:    p.Price,
+		Stock:    &stock,
+		Location: p.Location,
+		// Binding requires it to be true
+		IsAvailable: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/product/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.StrategyHeader, strategy)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func serverStrategies(ctx context.Context, client *http.Client, baseURL, adminToken string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v1/admin/strategy", nil)
+	if err != nil {
+		return nil, err
+	}
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, errors.New("the admin token is missing or wrong, pass --admin-token or set ADMIN_TOKEN, or list them with --strategies")
+	case http.StatusNotFound:
+		return nil, errors.New("the server doesn't serve the admin routes (production without ADMIN_TOKEN), list them with --strategies")
+	default:
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var show response.StrategyShow
+	if err := json.NewDecoder(res.Body).Decode(&show); err != nil {
+		return nil, err
+	}
+
+	return show.Available, nil
+}
+
+func writeBenchJSON(path string, result *benchResult) error {
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	return os.WriteFile(path, out, 0o644)
+}
+
+func writeBenchMarkdown(path string, reports []*loadgen.Report) error {
+	if path == "" {
+		return loadgen.WriteMarkdown(os.Stdout, reports)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return loadgen.WriteMarkdown(f, reports)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -1,20 +1,68 @@
 package config
 
 import (
-	"goroutines/pkg/env"
-	"os"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Trace exporters
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// EnvProduction is the ENV of production deployments
+const EnvProduction = "production"
+
+// Storage backends of the repositories
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
 )
 
 // Container contains environment variables for the application, database, cache, token, and http server
 type (
 	Container struct {
-		App *App
-		DB  *DB
+		App     *App
+		DB      *DB
+		Log     *Log
+		Health  *Health
+		Tracing *Tracing
+
+		// settings records where each value came from, for config print
+		settings map[string]Setting
 	}
 	// App contains all the environment variables for the application
 	App struct {
+		// Env is the deployment environment, EnvProduction hardens the defaults
+		Env  string
 		Port int
 		Host string
+		// CreateStrategy is the product create strategy used when a request does not pick one
+		CreateStrategy string
+		// Storage is StoragePostgres, or StorageMemory to run without a database
+		Storage string
+		// FixturesFile is a JSON file of categories and products loaded by the memory storage
+		FixturesFile string
+		// ShutdownTimeout bounds the graceful shutdown, in-flight requests included
+		ShutdownTimeout time.Duration
+		// ShutdownDrainDelay keeps serving with a failing readiness before the shutdown starts,
+		// so load balancers see it and stop routing here
+		ShutdownDrainDelay time.Duration
+		// AdminToken guards the debug endpoints, which are only served in production when it is set
+		AdminToken string
 	}
 	// Database contains all the environment variables for the database
 	DB struct {
@@ -24,31 +72,240 @@ type (
 		Name     string
 		Port     int
 		Params   string
+
+		// Replicas serve read only queries, they share the primary credentials
+		Replicas []Replica
+		// ReplicaPolicy picks a replica: "round-robin" or "least-conn"
+		ReplicaPolicy string
+		// ReplicaMaxLag drops a replica from rotation when its replay lag grows beyond it
+		ReplicaMaxLag time.Duration
+		// ReplicaCheckPeriod is the interval of the replica health checks
+		ReplicaCheckPeriod time.Duration
+
+		// Pool tuning, zero values keep the pgxpool defaults
+		Pool Pool
+
+		// SlowQueryThreshold logs statements running longer, 0 disables the log
+		SlowQueryThreshold time.Duration
+		// ExplainSampleRate is the share of slow statements whose plan is captured, from 0 to 1
+		ExplainSampleRate float64
+		// LogArgs logs the bound query arguments, only their count otherwise
+		LogArgs bool
+		// LogQueries logs every statement
+		LogQueries bool
+
+		// MigrateOnStart applies the pending migrations before serving, under an advisory lock
+		MigrateOnStart bool
+		// SchemaMismatch is what happens when the schema version differs from the binary's:
+		// "fail" refuses to start, "read-only" serves with read only transactions
+		SchemaMismatch string
+	}
+	// Log contains the logging settings
+	Log struct {
+		// Format is LogFormatText or LogFormatJSON
+		Format string
+		// Level is the minimum level written
+		Level slog.Level
+		// RedactKeys are attribute keys scrubbed from every log line, on top of the defaults
+		RedactKeys []string
+	}
+	// Health contains the readiness check settings
+	Health struct {
+		// CheckTimeout is the deadline of each readiness check
+		CheckTimeout time.Duration
+		// PoolSaturation fails readiness above this share of acquired connections, from 0 to 1
+		PoolSaturation float64
+		// CheckIpapi adds the reachability of ipapi.co as an optional check
+		CheckIpapi bool
+	}
+	// Tracing contains the OpenTelemetry settings
+	Tracing struct {
+		// Exporter is TracingNone, TracingStdout or TracingOTLP
+		Exporter string
+		// Endpoint is the host:port of the OTLP/HTTP collector
+		Endpoint string
+		// SampleRatio is the share of new traces recorded, from 0 to 1. Traces started upstream
+		// follow the sampling decision of their parent.
+		SampleRatio float64
+		// ServiceName identifies this process in the traces
+		ServiceName string
+	}
+	// Pool contains the pgxpool settings, applied to the primary and the replicas
+	Pool struct {
+		MaxConns              int32
+		MinConns              int32
+		MaxConnLifetime       time.Duration
+		MaxConnLifetimeJitter time.Duration
+		MaxConnIdleTime       time.Duration
+		HealthCheckPeriod     time.Duration
+		// AcquireWarnThreshold logs a warning when the average acquire wait crosses it, 0 disables it
+		AcquireWarnThreshold time.Duration
+	}
+	// Replica is the address of a read replica
+	Replica struct {
+		Host string
+		Port int
 	}
 )
 
+// New loads the configuration from the defaults and the environment
 func New() (*Container, error) {
+	return Load(Sources{})
+}
+
+// Load merges the defaults, the optional file, the environment and the flags, in increasing
+// precedence, then validates the result. Every invalid setting is reported at once.
+//
+// The container is returned along with the error, so `config print` can still show what was
+// read. A setting that failed to parse keeps its default. Nothing else should run with it.
+func Load(src Sources) (*Container, error) {
+	l := newLoader(src)
+
 	app := &App{
-		Port: 8080,
-		Host: "localhost",
+		Port:               8080,
+		Host:               "localhost",
+		CreateStrategy:     "goroutines",
+		Storage:            StoragePostgres,
+		ShutdownTimeout:    15 * time.Second,
+		ShutdownDrainDelay: 5 * time.Second,
+	}
+	l.string("ENV", &app.Env)
+	l.int("APP_PORT", &app.Port)
+	l.string("APP_HOST", &app.Host)
+	l.string("CREATE_STRATEGY", &app.CreateStrategy)
+	l.string("STORAGE", &app.Storage)
+	l.string("FIXTURES_FILE", &app.FixturesFile)
+	l.duration("SHUTDOWN_TIMEOUT", &app.ShutdownTimeout)
+	l.duration("SHUTDOWN_DRAIN_DELAY", &app.ShutdownDrainDelay)
+	l.secret("ADMIN_TOKEN", &app.AdminToken)
+
+	db := &DB{
+		ReplicaPolicy:      "round-robin",
+		ReplicaMaxLag:      10 * time.Second,
+		ReplicaCheckPeriod: 5 * time.Second,
+
+		SlowQueryThreshold: 200 * time.Millisecond,
+		ExplainSampleRate:  0.05,
+		LogArgs:            !app.IsProduction(),
+		LogQueries:         !app.IsProduction(),
+
+		SchemaMismatch: "fail",
+
+		Pool: Pool{
+			AcquireWarnThreshold: 100 * time.Millisecond,
+		},
 	}
+	l.string("DB_HOST", &db.Host)
+	l.string("DB_USERNAME", &db.Username)
+	l.secret("DB_PASSWORD", &db.Pass)
+	l.string("DB_NAME", &db.Name)
+	l.int("DB_PORT", &db.Port)
+	l.string("DB_PARAMS", &db.Params)
+	read(l, "DB_REPLICAS", &db.Replicas, false, parseReplicas)
+	l.string("DB_REPLICA_POLICY", &db.ReplicaPolicy)
+	l.duration("DB_REPLICA_MAX_LAG", &db.ReplicaMaxLag)
+	l.duration("DB_REPLICA_CHECK_PERIOD", &db.ReplicaCheckPeriod)
+	l.duration("DB_SLOW_QUERY_THRESHOLD", &db.SlowQueryThreshold)
+	l.float("DB_EXPLAIN_SAMPLE_RATE", &db.ExplainSampleRate)
+	l.bool("DB_LOG_ARGS", &db.LogArgs)
+	l.bool("DB_LOG_QUERIES", &db.LogQueries)
+	l.bool("MIGRATE_ON_START", &db.MigrateOnStart)
+	l.string("DB_SCHEMA_MISMATCH", &db.SchemaMismatch)
+	l.int32("DB_MAX_CONNS", &db.Pool.MaxConns)
+	l.int32("DB_MIN_CONNS", &db.Pool.MinConns)
+	l.duration("DB_MAX_CONN_LIFETIME", &db.Pool.MaxConnLifetime)
+	l.duration("DB_MAX_CONN_LIFETIME_JITTER", &db.Pool.MaxConnLifetimeJitter)
+	l.duration("DB_MAX_CONN_IDLE_TIME", &db.Pool.MaxConnIdleTime)
+	l.duration("DB_HEALTH_CHECK_PERIOD", &db.Pool.HealthCheckPeriod)
+	l.duration("DB_ACQUIRE_WARN_THRESHOLD", &db.Pool.AcquireWarnThreshold)
 
-	port, err := env.GetEnvInt("DB_PORT")
-	if err != nil {
-		return nil, err
+	log := &Log{
+		Format: LogFormatText,
+		Level:  slog.LevelInfo,
 	}
+	l.string("LOG_FORMAT", &log.Format)
+	read(l, "LOG_LEVEL", &log.Level, false, func(s string) (slog.Level, error) {
+		var level slog.Level
+		err := level.UnmarshalText([]byte(s))
+		return level, err
+	})
+	l.list("LOG_REDACT_KEYS", &log.RedactKeys)
 
-	db := &DB{
-		Host:     os.Getenv("DB_HOST"),
-		Username: os.Getenv("DB_USERNAME"),
-		Pass:     os.Getenv("DB_PASSWORD"),
-		Name:     os.Getenv("DB_NAME"),
-		Port:     port,
-		Params:   os.Getenv("DB_PARAMS"),
-	}
-
-	return &Container{
-		app,
-		db,
-	}, nil
+	health := &Health{
+		CheckTimeout:   time.Second,
+		PoolSaturation: 0.9,
+	}
+	l.duration("HEALTH_CHECK_TIMEOUT", &health.CheckTimeout)
+	l.float("HEALTH_POOL_SATURATION", &health.PoolSaturation)
+	l.bool("HEALTH_CHECK_IPAPI", &health.CheckIpapi)
+
+	tracing := &Tracing{
+		Exporter:    TracingNone,
+		Endpoint:    "localhost:4318",
+		SampleRatio: 1,
+		ServiceName: "goroutines",
+	}
+	l.string("TRACING_EXPORTER", &tracing.Exporter)
+	l.string("TRACING_OTLP_ENDPOINT", &tracing.Endpoint)
+	l.float("TRACING_SAMPLE_RATIO", &tracing.SampleRatio)
+	l.string("TRACING_SERVICE_NAME", &tracing.ServiceName)
+
+	l.unknownFileKeys()
+
+	c := &Container{
+		App:      app,
+		DB:       db,
+		Log:      log,
+		Health:   health,
+		Tracing:  tracing,
+		settings: l.settings,
+	}
+
+	return c, errors.Join(append(l.errs, c.validate(l.failed)...)...)
+}
+
+// IsProduction reports whether ENV is production
+func (a *App) IsProduction() bool {
+	return a.Env == EnvProduction
+}
+
+// Settings returns the effective value and source of every key, sorted by key
+func (c *Container) Settings() []Setting {
+	settings := make([]Setting, 0, len(c.settings))
+	for _, s := range c.settings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	return settings
+}
+
+// String describes the database connection with the password masked
+func (db *DB) String() string {
+	s := fmt.Sprintf("postgres://%s:***@%s:%d/%s?%s", db.Username, db.Host, db.Port, db.Name, db.Params)
+	for _, r := range db.Replicas {
+		s += fmt.Sprintf(" replica=%s:%d", r.Host, r.Port)
+	}
+
+	return s
+}
+
+// parseReplicas reads a comma separated list of host:port addresses
+func parseReplicas(s string) ([]Replica, error) {
+	var replicas []Replica
+	for _, addr := range strings.Split(s, ",") {
+		host, port, ok := strings.Cut(strings.TrimSpace(addr), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid replica address %q, expected host:port", addr)
+		}
+
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replica port %q: %w", addr, err)
+		}
+
+		replicas = append(replicas, Replica{Host: host, Port: p})
+	}
+
+	return replicas, nil
 }
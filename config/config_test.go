@@ -0,0 +1,144 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func setting(c *Container, key string) Setting {
+	for _, s := range c.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+	return Setting{}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("STORAGE", StorageMemory)
+	t.Setenv("APP_PORT", "9000")
+	t.Setenv("LOG_LEVEL", "warn")
+	file := writeFile(t, "app.yaml", `
+app:
+  port: 8500
+  host: api.local
+db:
+  max_conns: 20
+  replicas: ["db2:5432", "db3:5433"]
+log:
+  level: debug
+  redact_keys: [session, otp]
+health:
+  check_timeout: 3s
+`)
+
+	c, err := Load(Sources{File: file, Flags: map[string]string{"LOG_LEVEL": "error"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 9000, c.App.Port, "env over file")
+	assert.Equal(t, "api.local", c.App.Host, "file over default")
+	assert.Equal(t, slog.LevelError, c.Log.Level, "flag over env")
+	assert.Equal(t, int32(20), c.DB.Pool.MaxConns)
+	assert.Equal(t, []Replica{{"db2", 5432}, {"db3", 5433}}, c.DB.Replicas)
+	assert.Equal(t, []string{"session", "otp"}, c.Log.RedactKeys)
+	assert.Equal(t, 3*time.Second, c.Health.CheckTimeout)
+	assert.Equal(t, 15*time.Second, c.App.ShutdownTimeout, "default")
+
+	assert.Equal(t, Setting{Key: "APP_PORT", Value: "9000", Source: SourceEnv}, setting(c, "APP_PORT"))
+	assert.Equal(t, Setting{Key: "APP_HOST", Value: "api.local", Source: SourceFile}, setting(c, "APP_HOST"))
+	assert.Equal(t, Setting{Key: "LOG_LEVEL", Value: "ERROR", Source: SourceFlag}, setting(c, "LOG_LEVEL"))
+	assert.Equal(t, Setting{Key: "SHUTDOWN_TIMEOUT", Value: "15s", Source: SourceDefault}, setting(c, "SHUTDOWN_TIMEOUT"))
+}
+
+func TestLoadTOML(t *testing.T) {
+	t.Setenv("STORAGE", "")
+	t.Setenv("TRACING_EXPORTER", "")
+	file := writeFile(t, "app.toml", `
+storage = "memory"
+
+[tracing]
+exporter = "otlp"
+sample_ratio = 0.25
+`)
+
+	c, err := Load(Sources{File: file})
+	require.NoError(t, err)
+	assert.Equal(t, StorageMemory, c.App.Storage)
+	assert.Equal(t, TracingOTLP, c.Tracing.Exporter)
+	assert.Equal(t, 0.25, c.Tracing.SampleRatio)
+}
+
+func TestLoadEnvFromFile(t *testing.T) {
+	t.Setenv("STORAGE", StorageMemory)
+	t.Setenv("ENV", "")
+	t.Setenv("DB_LOG_ARGS", "")
+
+	c, err := Load(Sources{File: writeFile(t, "app.yaml", "env: production\n")})
+	require.NoError(t, err)
+	assert.True(t, c.App.IsProduction())
+	assert.False(t, c.DB.LogArgs, "production default")
+	assert.False(t, c.DB.LogQueries, "production default")
+	assert.Equal(t, Setting{Key: "ENV", Value: EnvProduction, Source: SourceFile}, setting(c, "ENV"))
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	t.Setenv("STORAGE", StorageMemory)
+	t.Setenv("ADMIN_TOKEN_FILE", writeFile(t, "token", "s3cret\n"))
+
+	c, err := Load(Sources{})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", c.App.AdminToken)
+	assert.Equal(t, Setting{Key: "ADMIN_TOKEN", Value: "s3cret", Source: SourceEnv, Secret: true}, setting(c, "ADMIN_TOKEN"))
+
+	t.Setenv("ADMIN_TOKEN", "other")
+	_, err = Load(Sources{})
+	assert.ErrorContains(t, err, "both ADMIN_TOKEN and ADMIN_TOKEN_FILE are set")
+
+	t.Setenv("ADMIN_TOKEN", "")
+	t.Setenv("ADMIN_TOKEN_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load(Sources{})
+	assert.ErrorContains(t, err, "ADMIN_TOKEN_FILE")
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	t.Setenv("STORAGE", "")
+	t.Setenv("DB_HOST", "")
+	t.Setenv("DB_PORT", "abc")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	t.Setenv("LOG_FORMAT", "xml")
+	file := writeFile(t, "app.yaml", "app:\n  prot: 1\n")
+
+	c, err := Load(Sources{File: file})
+	require.Error(t, err)
+	// Still printable, the value that doesn't parse keeps its default
+	assert.Equal(t, Setting{Key: "DB_PORT", Value: "0", Source: SourceDefault}, setting(c, "DB_PORT"))
+
+	for _, want := range []string{
+		`DB_PORT="abc" (env): invalid syntax`,
+		"APP_PROT (file): unknown setting",
+		"DB_HOST: required with the postgres storage",
+		"TRACING_SAMPLE_RATIO: 2 out of range 0-1",
+		`LOG_FORMAT: "xml" is not one of text, json`,
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+	assert.NotContains(t, err.Error(), "DB_PORT: port", "a value that doesn't parse is reported once")
+}
+
+func TestLoadUnsupportedFile(t *testing.T) {
+	_, err := Load(Sources{File: writeFile(t, "app.json", "{}")})
+	assert.ErrorContains(t, err, "unsupported extension")
+}
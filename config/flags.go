@@ -0,0 +1,66 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+// flagKeys are the settings also accepted on the command line
+var flagKeys = []struct {
+	name, key, usage string
+}{
+	{"env", "ENV", "deployment environment, production hardens the defaults"},
+	{"port", "APP_PORT", "HTTP port"},
+	{"host", "APP_HOST", "host name the server is reached at"},
+	{"storage", "STORAGE", "repositories backend: postgres or memory"},
+	{"fixtures", "FIXTURES_FILE", "JSON fixtures loaded by the memory storage"},
+	{"create-strategy", "CREATE_STRATEGY", "product create strategy used when a request does not pick one"},
+	{"log-format", "LOG_FORMAT", "log format: text or json"},
+	{"log-level", "LOG_LEVEL", "minimum log level: debug, info, warn or error"},
+	{"tracing-exporter", "TRACING_EXPORTER", "trace exporter: none, stdout or otlp"},
+}
+
+// Flags are the configuration flags bound to a flag set
+type Flags struct {
+	fs     *flag.FlagSet
+	file   *string
+	values map[string]*string
+}
+
+// BindFlags defines --config and a flag per command line setting on fs
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{
+		fs:     fs,
+		file:   fs.String("config", "", "YAML or TOML configuration file (env CONFIG_FILE)"),
+		values: map[string]*string{},
+	}
+	for _, k := range flagKeys {
+		f.values[k.name] = fs.String(k.name, "", k.usage+" (env "+k.key+")")
+	}
+
+	return f
+}
+
+// Sources returns the configuration file and the flags set on the command line once fs is
+// parsed. Flags left unset don't hide the lower layers.
+func (f *Flags) Sources() Sources {
+	src := Sources{
+		File:  *f.file,
+		Flags: map[string]string{},
+	}
+	if src.File == "" {
+		src.File = os.Getenv("CONFIG_FILE")
+	}
+
+	keys := make(map[string]string, len(flagKeys))
+	for _, k := range flagKeys {
+		keys[k.name] = k.key
+	}
+	f.fs.Visit(func(fl *flag.Flag) {
+		if key, ok := keys[fl.Name]; ok {
+			src.Flags[key] = *f.values[fl.Name]
+		}
+	})
+
+	return src
+}
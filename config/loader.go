@@ -0,0 +1,279 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Setting sources, from the lowest to the highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// fileSuffix reads any setting from a file when appended to its key, DB_PASSWORD_FILE=/run/secrets/db
+const fileSuffix = "_FILE"
+
+// Sources are the layers merged over the defaults. The environment always takes part.
+type Sources struct {
+	// File is an optional YAML (.yaml, .yml) or TOML (.toml) file
+	File string
+	// Flags hold the command line values by setting key, they win over everything
+	Flags map[string]string
+}
+
+// Setting is the effective value of a configuration key and the source it came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+	Secret bool
+}
+
+// layer is a source of raw values by setting key
+type layer struct {
+	name   string
+	lookup func(key string) (string, bool)
+}
+
+// loader reads typed settings through the layers, collecting every error instead of stopping
+// at the first one
+type loader struct {
+	layers   []layer
+	file     map[string]string
+	settings map[string]Setting
+	// failed are the keys already reported, validation skips them
+	failed map[string]bool
+	errs   []error
+}
+
+func newLoader(src Sources) *loader {
+	l := &loader{settings: map[string]Setting{}, failed: map[string]bool{}}
+
+	// Highest precedence first
+	l.layers = append(l.layers, layer{SourceFlag, func(key string) (string, bool) {
+		v, ok := src.Flags[key]
+		return v, ok
+	}})
+	l.layers = append(l.layers, layer{SourceEnv, func(key string) (string, bool) {
+		v := os.Getenv(key)
+		return v, v != ""
+	}})
+	if src.File != "" {
+		file, err := readFile(src.File)
+		if err != nil {
+			l.errs = append(l.errs, err)
+		}
+		l.file = file
+		l.layers = append(l.layers, layer{SourceFile, func(key string) (string, bool) {
+			v, ok := file[key]
+			return v, ok
+		}})
+	}
+
+	return l
+}
+
+// raw returns the value of key from the first layer defining it, or KEY_FILE's content
+func (l *loader) raw(key string) (value, source string, ok bool) {
+	for _, layer := range l.layers {
+		v, found := layer.lookup(key)
+		path, fromFile := layer.lookup(key + fileSuffix)
+		if found && fromFile {
+			l.fail(key, fmt.Errorf("%s: both %s and %s%s are set (%s)", key, key, key, fileSuffix, layer.name))
+			return "", layer.name, false
+		}
+		if found {
+			return v, layer.name, true
+		}
+		if fromFile {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				l.fail(key, fmt.Errorf("%s%s: %w", key, fileSuffix, err))
+				return "", layer.name, false
+			}
+			return strings.TrimRight(string(content), "\r\n"), layer.name, true
+		}
+	}
+
+	return "", SourceDefault, false
+}
+
+func (l *loader) fail(key string, err error) {
+	l.failed[key] = true
+	l.errs = append(l.errs, err)
+}
+
+// read parses the raw value of key into dst with parse, leaving dst as the default when unset
+func read[T any](l *loader, key string, dst *T, secret bool, parse func(string) (T, error)) {
+	raw, source, ok := l.raw(key)
+	if ok {
+		v, err := parse(raw)
+		if err != nil {
+			l.fail(key, fmt.Errorf("%s=%q (%s): %w", key, raw, source, unwrapNum(err)))
+			source = SourceDefault
+		} else {
+			*dst = v
+		}
+	}
+
+	l.settings[key] = Setting{Key: key, Value: formatValue(*dst), Source: source, Secret: secret}
+}
+
+// formatValue prints lists the way they are written, comma separated
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []Replica:
+		addrs := make([]string, len(v))
+		for i, r := range v {
+			addrs[i] = fmt.Sprintf("%s:%d", r.Host, r.Port)
+		}
+		return strings.Join(addrs, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (l *loader) string(key string, dst *string) {
+	read(l, key, dst, false, func(s string) (string, error) { return s, nil })
+}
+
+func (l *loader) secret(key string, dst *string) {
+	read(l, key, dst, true, func(s string) (string, error) { return s, nil })
+}
+
+func (l *loader) int(key string, dst *int) {
+	read(l, key, dst, false, strconv.Atoi)
+}
+
+func (l *loader) int32(key string, dst *int32) {
+	read(l, key, dst, false, func(s string) (int32, error) {
+		v, err := strconv.ParseInt(s, 10, 32)
+		return int32(v), err
+	})
+}
+
+func (l *loader) float(key string, dst *float64) {
+	read(l, key, dst, false, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+}
+
+func (l *loader) bool(key string, dst *bool) {
+	read(l, key, dst, false, strconv.ParseBool)
+}
+
+func (l *loader) duration(key string, dst *time.Duration) {
+	read(l, key, dst, false, time.ParseDuration)
+}
+
+// list reads a comma separated list
+func (l *loader) list(key string, dst *[]string) {
+	read(l, key, dst, false, func(s string) ([]string, error) {
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	})
+}
+
+// unknownFileKeys reports the file settings no field reads, typos mostly
+func (l *loader) unknownFileKeys() {
+	var unknown []string
+	for key := range l.file {
+		if _, ok := l.settings[key]; ok {
+			continue
+		}
+		if _, ok := l.settings[strings.TrimSuffix(key, fileSuffix)]; ok && strings.HasSuffix(key, fileSuffix) {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+
+	for _, key := range unknown {
+		l.errs = append(l.errs, fmt.Errorf("%s (%s): unknown setting", key, SourceFile))
+	}
+}
+
+// unwrapNum drops the strconv function name from parse errors
+func unwrapNum(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
+
+// readFile flattens a YAML or TOML file into setting keys: nested tables join their names with
+// underscores, db.max_conns is DB_MAX_CONNS, and lists join their items with commas
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	tree := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &tree)
+	case ".toml":
+		err = toml.Unmarshal(content, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	flat := map[string]string{}
+	if err := flatten("", tree, flat); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return flat, nil
+}
+
+func flatten(prefix string, tree map[string]any, flat map[string]string) error {
+	for name, v := range tree {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(key, v, flat); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				if _, nested := item.(map[string]any); nested {
+					return fmt.Errorf("%s: lists hold plain values only", key)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			flat[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			flat[key] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// validate checks every field and returns all the problems found, skipping the keys in failed
+// that could not be read in the first place
+func (c *Container) validate(failed map[string]bool) []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok && !failed[key] {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+	port := func(key string, p int) {
+		check(p > 0 && p < 1<<16, key, "port %d out of range 1-65535", p)
+	}
+	ratio := func(key string, f float64) {
+		check(f >= 0 && f <= 1, key, "%v out of range 0-1", f)
+	}
+	positive := func(key string, d time.Duration) {
+		check(d > 0, key, "%s must be positive", d)
+	}
+	notNegative := func(key string, d time.Duration) {
+		check(d >= 0, key, "%s must not be negative", d)
+	}
+
+	app := c.App
+	port("APP_PORT", app.Port)
+	check(app.CreateStrategy != "", "CREATE_STRATEGY", "must not be empty")
+	oneOf("STORAGE", app.Storage, StoragePostgres, StorageMemory)
+	positive("SHUTDOWN_TIMEOUT", app.ShutdownTimeout)
+	notNegative("SHUTDOWN_DRAIN_DELAY", app.ShutdownDrainDelay)
+
+	// The memory storage runs without any database setting
+	db := c.DB
+	if app.Storage == StoragePostgres {
+		check(db.Host != "", "DB_HOST", "required with the %s storage", StoragePostgres)
+		check(db.Username != "", "DB_USERNAME", "required with the %s storage", StoragePostgres)
+		check(db.Name != "", "DB_NAME", "required with the %s storage", StoragePostgres)
+		port("DB_PORT", db.Port)
+	}
+	for _, r := range db.Replicas {
+		check(r.Host != "", "DB_REPLICAS", "replica without host")
+		port("DB_REPLICAS", r.Port)
+	}
+	oneOf("DB_REPLICA_POLICY", db.ReplicaPolicy, "round-robin", "least-conn")
+	notNegative("DB_REPLICA_MAX_LAG", db.ReplicaMaxLag)
+	positive("DB_REPLICA_CHECK_PERIOD", db.ReplicaCheckPeriod)
+	notNegative("DB_SLOW_QUERY_THRESHOLD", db.SlowQueryThreshold)
+	ratio("DB_EXPLAIN_SAMPLE_RATE", db.ExplainSampleRate)
+	oneOf("DB_SCHEMA_MISMATCH", db.SchemaMismatch, "fail", "read-only")
+
+	pool := db.Pool
+	check(pool.MaxConns >= 0, "DB_MAX_CONNS", "%d must not be negative", pool.MaxConns)
+	check(pool.MinConns >= 0, "DB_MIN_CONNS", "%d must not be negative", pool.MinConns)
+	check(pool.MaxConns == 0 || pool.MinConns <= pool.MaxConns, "DB_MIN_CONNS", "%d exceeds DB_MAX_CONNS %d", pool.MinConns, pool.MaxConns)
+	notNegative("DB_MAX_CONN_LIFETIME", pool.MaxConnLifetime)
+	notNegative("DB_MAX_CONN_LIFETIME_JITTER", pool.MaxConnLifetimeJitter)
+	notNegative("DB_MAX_CONN_IDLE_TIME", pool.MaxConnIdleTime)
+	notNegative("DB_HEALTH_CHECK_PERIOD", pool.HealthCheckPeriod)
+	notNegative("DB_ACQUIRE_WARN_THRESHOLD", pool.AcquireWarnThreshold)
+
+	oneOf("LOG_FORMAT", c.Log.Format, LogFormatText, LogFormatJSON)
+
+	positive("HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout)
+	ratio("HEALTH_POOL_SATURATION", c.Health.PoolSaturation)
+
+	tracing := c.Tracing
+	oneOf("TRACING_EXPORTER", tracing.Exporter, TracingNone, TracingStdout, TracingOTLP)
+	check(tracing.Exporter != TracingOTLP || tracing.Endpoint != "", "TRACING_OTLP_ENDPOINT", "required with the %s exporter", TracingOTLP)
+	ratio("TRACING_SAMPLE_RATIO", tracing.SampleRatio)
+	check(tracing.ServiceName != "", "TRACING_SERVICE_NAME", "must not be empty")
+
+	return errs
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"goroutines/config"
+	"goroutines/pkg/logging"
+	"os"
+	"text/tabwriter"
+)
+
+const configUsage = `usage: main config <command>
+
+commands:
+  print           print the effective settings and where each came from, secrets redacted`
+
+// runConfig runs the config subcommand and returns the process exit code. The settings of an
+// invalid configuration are printed as well, followed by loadErr, and the exit code is 1.
+func runConfig(cfg *config.Container, loadErr error, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range cfg.Settings() {
+		value := s.Value
+		if s.Secret && value != "" {
+			value = logging.Redacted
+		}
+		fmt.Fprintf(w, "%s=%s\t# %s\n", s.Key, value, s.Source)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to print config: %v\n", err)
+		return 1
+	}
+
+	if loadErr != nil {
+		fmt.Fprintf(os.Stderr, "\nInvalid configuration:\n%v\n", loadErr)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,2 @@
+DELETE FROM categories
+WHERE "name" IN ('Clothing', 'Accessories', 'Footwear', 'Beverages') AND "deleted_at" IS NULL;
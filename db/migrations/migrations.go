@@ -0,0 +1,39 @@
+// Package migrations embeds the SQL migrations so the binary can apply them itself.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FS holds every <version>_<name>.(up|down).sql file of this directory
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Version returns the latest migration version embedded in FS, the schema version this binary expects
+func Version() (uint, error) {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint64
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok || !strings.HasSuffix(e.Name(), ".up.sql") {
+			continue
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", e.Name(), err)
+		}
+		latest = max(latest, version)
+	}
+
+	return uint(latest), nil
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion(t *testing.T) {
+	version, err := Version()
+	assert.NoError(t, err)
+
+	ups, _ := fs.Glob(FS, fmt.Sprintf("%d_*.up.sql", version))
+	downs, _ := fs.Glob(FS, fmt.Sprintf("%d_*.down.sql", version))
+	assert.Len(t, ups, 1)
+	assert.Len(t, downs, 1)
+}
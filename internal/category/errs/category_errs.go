@@ -0,0 +1,12 @@
+package errs
+
+import "errors"
+
+var (
+	CategoryErrsAlreadyExists = errors.New("Category already exists")
+)
+
+// Constraints maps the categories table constraints onto the category sentinels
+var Constraints = map[string]error{
+	"product_category": CategoryErrsAlreadyExists,
+}
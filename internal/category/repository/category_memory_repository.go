@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	domain "goroutines/internal/category"
+	"goroutines/pkg/database/memory"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CategoryMemoryRepository is a thread-safe in-memory CategoryRepository. Like the categories
+// table it generates the id and created_at of new rows and keeps live names unique.
+type CategoryMemoryRepository struct {
+	mu         sync.RWMutex
+	categories []domain.Category
+}
+
+var _ CategoryRepository = (*CategoryMemoryRepository)(nil)
+
+func NewCategoryMemoryRepository() *CategoryMemoryRepository {
+	return &CategoryMemoryRepository{}
+}
+
+// GetReferenceByName returns a copy of the category, pgx.ErrNoRows when there is none
+func (cr *CategoryMemoryRepository) GetReferenceByName(ctx context.Context, name string) (*domain.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	for _, c := range cr.categories {
+		if c.Name == name {
+			return &c, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// Persist stores a copy of c and fills its generated columns, undone if the transaction carried by ctx rolls back.
+// Two categories not deleted can't share a name, as enforced by the product_category index.
+func (cr *CategoryMemoryRepository) Persist(ctx context.Context, c *domain.Category) (*domain.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	row := *c
+	if row.ID.IsNil() {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		row.ID = id
+	}
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = time.Now()
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for _, existing := range cr.categories {
+		if existing.ID == row.ID {
+			return nil, memory.UniqueViolation("categories", "product_category_pkey", fmt.Sprintf("Key (id)=(%s) already exists.", row.ID))
+		}
+		if existing.Name == row.Name && existing.DeletedAt == nil && row.DeletedAt == nil {
+			return nil, memory.UniqueViolation("categories", "product_category", fmt.Sprintf("Key (name)=(%s) already exists.", row.Name))
+		}
+	}
+	cr.categories = append(cr.categories, row)
+
+	memory.OnRollback(ctx, func() {
+		cr.mu.Lock()
+		defer cr.mu.Unlock()
+		for i, existing := range cr.categories {
+			if existing.ID == row.ID {
+				cr.categories = append(cr.categories[:i], cr.categories[i+1:]...)
+				return
+			}
+		}
+	})
+
+	*c = row
+	return c, nil
+}
+
+// All returns a copy of every category, sorted by name
+func (cr *CategoryMemoryRepository) All() []*domain.Category {
+	cr.mu.RLock()
+	categories := make([]*domain.Category, 0, len(cr.categories))
+	for _, c := range cr.categories {
+		categories = append(categories, &c)
+	}
+	cr.mu.RUnlock()
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	domain "goroutines/internal/category"
 	"goroutines/pkg/database"
+	"goroutines/pkg/logging"
 	"log/slog"
 
 	sq "github.com/Masterminds/squirrel"
@@ -39,7 +40,7 @@ func (cr *categoryRepository) GetReferenceByName(ctx context.Context, name strin
 		return nil, err
 	}
 
-	rows, err := cr.db.Query(ctx, sql, args...)
+	rows, err := cr.db.Reader(ctx).Query(ctx, sql, args...)
 	if err == nil {
 		category, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[domain.Category])
 	}
@@ -48,7 +49,7 @@ func (cr *categoryRepository) GetReferenceByName(ctx context.Context, name strin
 	}
 
 	if err != nil {
-		slog.Error("cannot get category from database",
+		logging.FromContext(ctx).Error("cannot get category from database",
 			slog.Any("name", name),
 			slog.Any("error", err))
 		return nil, errors.New("cannot get category from database")
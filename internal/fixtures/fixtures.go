@@ -0,0 +1,62 @@
+// Package fixtures fills the in-memory repositories used when running without a database.
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goroutines/internal/category"
+	categoryRepository "goroutines/internal/category/repository"
+	"goroutines/internal/product"
+	"goroutines/internal/product/repository"
+	dbErrs "goroutines/pkg/database/errs"
+	"os"
+)
+
+// DefaultCategories are the categories of the seeder migration
+var DefaultCategories = []string{"Clothing", "Accessories", "Footwear", "Beverages"}
+
+// Fixtures is the content of a fixtures file, fields use the JSON names of the API, e.g.
+//
+//	{"categories": [{"name": "Books"}], "products": [{"name": "Go", "sku": "B-1", "category": "Books", ...}]}
+type Fixtures struct {
+	Categories []category.Category `json:"categories"`
+	Products   []product.Product   `json:"products"`
+}
+
+// Load stores the default categories, then the categories and products of the file at path
+// when it is not empty. Categories already stored are skipped.
+func Load(ctx context.Context, path string, products *repository.ProductMemoryRepository, categories *categoryRepository.CategoryMemoryRepository) error {
+	f := &Fixtures{}
+	for _, name := range DefaultCategories {
+		f.Categories = append(f.Categories, category.Category{Name: name})
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read fixtures: %w", err)
+		}
+
+		var file Fixtures
+		if err := json.Unmarshal(content, &file); err != nil {
+			return fmt.Errorf("decode fixtures %s: %w", path, err)
+		}
+		f.Categories = append(f.Categories, file.Categories...)
+		f.Products = file.Products
+	}
+
+	for _, c := range f.Categories {
+		if _, err := categories.Persist(ctx, &c); err != nil && !errors.Is(err, dbErrs.ErrUniqueViolation) {
+			return fmt.Errorf("load category %q: %w", c.Name, err)
+		}
+	}
+	for _, p := range f.Products {
+		if _, err := products.Persist(ctx, &p); err != nil {
+			return fmt.Errorf("load product %q: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -1,86 +1,59 @@
 package controller
 
 import (
-	"errors"
-	"goroutines/internal/product/errs"
 	"goroutines/internal/product/request"
 	"goroutines/internal/product/response"
 	"goroutines/internal/product/service"
+	"goroutines/router/middleware"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
+// StrategyQuery selects the create strategy when middleware.StrategyHeader is absent
+const StrategyQuery = "strategy"
+
 type ProductController interface {
 	CreateProduct(ctx *gin.Context)
-	CreateProductGoroutines(ctx *gin.Context)
-	CreateProductGoroutinesIncrease(ctx *gin.Context)
-	CreateProductTx(ctx *gin.Context)
+	GetStrategy(ctx *gin.Context)
+	SetStrategy(ctx *gin.Context)
 }
 
 type productController struct {
-	svc service.ProductService
+	strategies *service.StrategyRegistry
 }
 
-func NewProductController(svc service.ProductService) ProductController {
-	return &productController{svc}
+func NewProductController(strategies *service.StrategyRegistry) ProductController {
+	return &productController{strategies}
 }
 
 func (c *productController) CreateProduct(ctx *gin.Context) {
-	var reqBody request.ProductCreateRequest
-	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, err)
-		return
+	name := ctx.GetHeader(middleware.StrategyHeader)
+	if name == "" {
+		name = ctx.Query(StrategyQuery)
 	}
-	if reqBody.ValidateProductCreate() != nil {
-		validateErr := reqBody.ValidateProductCreate()
 
-		ctx.AbortWithError(http.StatusBadRequest, validateErr)
-		return
-	}
-
-	productCreated, err := c.svc.CreateProduct(&reqBody)
+	name, create, err := c.strategies.Resolve(name)
 	if err != nil {
-		switch {
-		case errors.Is(err, errs.ProductErrsCategoryNotFound):
-			ctx.AbortWithError(http.StatusBadRequest, err)
-			break
-		default:
-			ctx.AbortWithError(http.StatusInternalServerError, err)
-			break
-		}
-
+		ctx.Error(err)
 		return
 	}
+	ctx.Header(middleware.StrategyHeader, name)
 
-	productCreatedMappedResult := response.ProductToCreateResponse(productCreated)
-	ctx.JSON(http.StatusCreated, productCreatedMappedResult)
-}
-
-func (c *productController) CreateProductGoroutines(ctx *gin.Context) {
 	var reqBody request.ProductCreateRequest
 	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, err)
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
-	if reqBody.ValidateProductCreate() != nil {
-		validateErr := reqBody.ValidateProductCreate()
-
-		ctx.AbortWithError(http.StatusBadRequest, validateErr)
+	if validateErr := reqBody.ValidateProductCreate(); validateErr != nil {
+		ctx.Error(validateErr)
 		return
 	}
 
-	productCreated := <-c.svc.CreateProductGoroutines(&reqBody)
+	// Status codes are resolved by middleware.ErrorHandler from the statuses the router registered
+	productCreated := <-create(ctx.Request.Context(), &reqBody)
 	if productCreated.Error != nil {
-		switch {
-		case errors.Is(productCreated.Error, errs.ProductErrsCategoryNotFound):
-			ctx.AbortWithError(http.StatusBadRequest, productCreated.Error)
-			break
-		default:
-			ctx.AbortWithError(http.StatusInternalServerError, productCreated.Error)
-			break
-		}
-
+		ctx.Error(productCreated.Error)
 		return
 	}
 
@@ -88,64 +61,27 @@ func (c *productController) CreateProductGoroutines(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, productCreatedMappedResult)
 }
 
-func (c *productController) CreateProductGoroutinesIncrease(ctx *gin.Context) {
-	var reqBody request.ProductCreateRequest
-	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, err)
-		return
-	}
-	if reqBody.ValidateProductCreate() != nil {
-		validateErr := reqBody.ValidateProductCreate()
-
-		ctx.AbortWithError(http.StatusBadRequest, validateErr)
-		return
-	}
-
-	productCreated := <-c.svc.CreateProductGoroutinesBuffered(&reqBody)
-	if productCreated.Error != nil {
-		switch {
-		case errors.Is(productCreated.Error, errs.ProductErrsCategoryNotFound):
-			ctx.AbortWithError(http.StatusBadRequest, productCreated.Error)
-			break
-		default:
-			ctx.AbortWithError(http.StatusInternalServerError, productCreated.Error)
-			break
-		}
-
-		return
-	}
-
-	productCreatedMappedResult := response.ProductToCreateResponse(productCreated.Result)
-	ctx.JSON(http.StatusCreated, productCreatedMappedResult)
+func (c *productController) GetStrategy(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, response.StrategyShow{
+		Default:   c.strategies.Default(),
+		Available: c.strategies.Names(),
+	})
 }
 
-func (c *productController) CreateProductTx(ctx *gin.Context) {
-	var reqBody request.ProductCreateRequest
+func (c *productController) SetStrategy(ctx *gin.Context) {
+	var reqBody request.StrategyUpdateRequest
 	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, err)
-		return
-	}
-	if reqBody.ValidateProductCreate() != nil {
-		validateErr := reqBody.ValidateProductCreate()
-
-		ctx.AbortWithError(http.StatusBadRequest, validateErr)
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	productCreated, err := c.svc.CreateProductTx(&reqBody)
-	if err != nil {
-		switch {
-		case errors.Is(err, errs.ProductErrsCategoryNotFound):
-			ctx.AbortWithError(http.StatusBadRequest, err)
-			break
-		default:
-			ctx.AbortWithError(http.StatusInternalServerError, err)
-			break
-		}
-
+	if err := c.strategies.SetDefault(reqBody.Strategy); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	productCreatedMappedResult := response.ProductToCreateResponse(productCreated)
-	ctx.JSON(http.StatusCreated, productCreatedMappedResult)
+	ctx.JSON(http.StatusOK, response.StrategyShow{
+		Default:   c.strategies.Default(),
+		Available: c.strategies.Names(),
+	})
 }
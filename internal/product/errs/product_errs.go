@@ -9,6 +9,8 @@ var (
 	ProductErrsCategoryNotFound = errors.New("Product category not found")
 	ProductErrsSkuOverflow      = errors.New("Product sku is overflow")
 	ProductErrsImageUrlInvalid  = errors.New("Product image url invalid")
+	ProductErrsBatchDisabled    = errors.New("Product batch writer is not configured")
+	ProductErrsStrategyUnknown  = errors.New("Product create strategy unknown")
 )
 
 type ProductErrs struct {
@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	domain "goroutines/internal/product"
+	"goroutines/pkg/database"
+	"goroutines/pkg/logging"
+	"goroutines/pkg/tracing"
+	"goroutines/util"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultBatchMaxRows is the number of rows that forces a flush
+	DefaultBatchMaxRows = 100
+	// DefaultBatchMaxDelay is how long the first row of a batch waits for company
+	DefaultBatchMaxDelay = 5 * time.Millisecond
+)
+
+var ErrBatchWriterClosed = errors.New("batch writer is closed")
+
+// ProductBatchWriter groups concurrent Persist calls into a single pgx batch (group commit).
+//
+// Every caller receives its own result channel. A row that fails only fails its own caller,
+// the remaining rows of the batch are still written.
+type ProductBatchWriter struct {
+	db       *database.DB
+	maxRows  int
+	maxDelay time.Duration
+
+	queue chan *batchItem
+	// closing is closed once the writer stops, waking the callers blocked on a full queue
+	closing chan struct{}
+	// mu guards closed: senders hold it shared while enqueueing, so once the collector took it
+	// exclusively and set closed, nothing can land in the queue after its final drain
+	mu     sync.RWMutex
+	closed bool
+}
+
+type batchItem struct {
+	ctx     context.Context
+	product *domain.Product
+	result  chan util.Result[*domain.Product]
+}
+
+func NewProductBatchWriter(db *database.DB, maxRows int, maxDelay time.Duration) *ProductBatchWriter {
+	if maxRows <= 0 {
+		maxRows = DefaultBatchMaxRows
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultBatchMaxDelay
+	}
+
+	return &ProductBatchWriter{
+		db:       db,
+		maxRows:  maxRows,
+		maxDelay: maxDelay,
+		queue:    make(chan *batchItem, maxRows),
+		closing:  make(chan struct{}),
+	}
+}
+
+// Start runs the collector loop until ctx is canceled
+func (w *ProductBatchWriter) Start(ctx context.Context) {
+	util.GoSafe("product batch writer", func() { w.run(ctx) })
+}
+
+// Persist queues p for the next flush. The returned channel receives exactly one value.
+func (w *ProductBatchWriter) Persist(ctx context.Context, p *domain.Product) <-chan util.Result[*domain.Product] {
+	// Buffered, so the flusher never blocks on a caller that went away
+	result := make(chan util.Result[*domain.Product], 1)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		result <- util.Result[*domain.Product]{Error: ErrBatchWriterClosed}
+		return result
+	}
+
+	select {
+	case w.queue <- &batchItem{ctx, p, result}:
+	case <-w.closing:
+		result <- util.Result[*domain.Product]{Error: ErrBatchWriterClosed}
+	case <-ctx.Done():
+		result <- util.Result[*domain.Product]{Error: ctx.Err()}
+	}
+
+	return result
+}
+
+func (w *ProductBatchWriter) run(ctx context.Context) {
+	for {
+		// Block until the first row of the next batch arrives
+		var first *batchItem
+		select {
+		case first = <-w.queue:
+		case <-ctx.Done():
+			w.close(ctx.Err())
+			return
+		}
+
+		items := []*batchItem{first}
+		timer := time.NewTimer(w.maxDelay)
+	collect:
+		for len(items) < w.maxRows {
+			select {
+			case item := <-w.queue:
+				items = append(items, item)
+			case <-timer.C:
+				break collect
+			case <-ctx.Done():
+				break collect
+			}
+		}
+		timer.Stop()
+
+		w.safeFlush(ctx, items)
+	}
+}
+
+// safeFlush keeps the collector alive when a flush panics. Callers that were not answered
+// before the panic receive it as their error.
+func (w *ProductBatchWriter) safeFlush(ctx context.Context, items []*batchItem) {
+	defer func() {
+		if err := util.Recover(recover()); err != nil {
+			for _, item := range items {
+				select {
+				case item.result <- util.Result[*domain.Product]{Error: err}:
+				default:
+				}
+			}
+		}
+	}()
+
+	w.flush(ctx, items)
+}
+
+// close stops accepting rows and fails everything still queued with err. A sender may still win
+// the queue once closing is closed, so the drain waits for every sender to leave first.
+func (w *ProductBatchWriter) close(err error) {
+	close(w.closing)
+
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	for {
+		select {
+		case item := <-w.queue:
+			item.result <- util.Result[*domain.Product]{Error: err}
+		default:
+			return
+		}
+	}
+}
+
+// flush writes items as one transaction, so errors never leak to other callers: a failing row
+// only gets its own error. Serialization failures and deadlocks are not the row's fault, they
+// run the whole transaction again.
+func (w *ProductBatchWriter) flush(ctx context.Context, items []*batchItem) {
+	pending := make([]*batchItem, 0, len(items))
+	for _, item := range items {
+		if err := item.ctx.Err(); err != nil {
+			item.result <- util.Result[*domain.Product]{Error: err}
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	// The batch serves many requests, so its span starts a trace linked to each of them
+	links := make([]trace.Link, 0, len(pending))
+	for _, item := range pending {
+		links = append(links, trace.LinkFromContext(item.ctx))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "ProductBatchWriter.flush",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("batch.rows", len(pending))))
+	defer span.End()
+
+	// Scan into copies so a rolled back attempt leaves the callers' products untouched
+	var (
+		scanned  []domain.Product
+		rejected []error
+	)
+	err := w.db.BeginTransaction(ctx, func(tx pgx.Tx, ctx context.Context) error {
+		scanned = make([]domain.Product, len(pending))
+		rejected = make([]error, len(pending))
+		return w.write(ctx, tx, pending, scanned, rejected)
+	})
+	if err != nil {
+		span.RecordError(err)
+		for _, item := range pending {
+			item.result <- util.Result[*domain.Product]{Error: err}
+		}
+		return
+	}
+
+	for i, item := range pending {
+		if rejected[i] != nil {
+			item.result <- util.Result[*domain.Product]{Error: rejected[i]}
+			continue
+		}
+		*item.product = scanned[i]
+		item.result <- util.Result[*domain.Product]{Result: item.product}
+	}
+}
+
+// write sends items in runs, each inside its own savepoint. When a row fails its run is rolled
+// back, the row is rejected and the rows before and after it become two new runs, so a row is
+// sent at most twice however many rows of the batch fail.
+func (w *ProductBatchWriter) write(ctx context.Context, tx pgx.Tx, items []*batchItem, scanned []domain.Product, rejected []error) error {
+	runs := [][2]int{{0, len(items)}}
+	for len(runs) > 0 {
+		lo, hi := runs[0][0], runs[0][1]
+		runs = runs[1:]
+		if lo == hi {
+			continue
+		}
+
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		failedAt, rowErr, err := w.send(ctx, savepoint, items[lo:hi], scanned[lo:hi])
+		if err != nil {
+			return err
+		}
+		if failedAt < 0 {
+			if err := savepoint.Commit(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := savepoint.Rollback(ctx); err != nil {
+			return err
+		}
+		failed := lo + failedAt
+		rejected[failed] = rowErr
+		runs = append([][2]int{{lo, failed}, {failed + 1, hi}}, runs...)
+	}
+
+	return nil
+}
+
+// send runs a single batch. It returns the index of the first failing row (or -1) with its error,
+// and a non-nil err only for failures that are not attributable to a single row.
+func (w *ProductBatchWriter) send(ctx context.Context, tx pgx.Tx, items []*batchItem, scanned []domain.Product) (int, error, error) {
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		sql, args, err := insertProductQuery(w.db, item.product)
+		if err != nil {
+			return -1, nil, err
+		}
+		batch.Queue(sql, args...)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	for i, item := range items {
+		scanned[i] = *item.product
+		if err := results.QueryRow().Scan(returningDest(&scanned[i])...); err != nil {
+			results.Close()
+
+			if database.IsRetryable(err) {
+				return -1, nil, err
+			}
+			if sqlErr := w.db.ErrorCode(err); sqlErr != nil {
+				return i, sqlErr, nil
+			}
+			// Logged with the logger of the request the row belongs to
+			logging.FromContext(item.ctx).Error("Cannot persist product batch on database",
+				slog.Int("row", i),
+				slog.Any("error", err))
+			return i, err, nil
+		}
+	}
+
+	return -1, nil, results.Close()
+}
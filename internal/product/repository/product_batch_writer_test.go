@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"goroutines/config"
+	domain "goroutines/internal/product"
+	"goroutines/pkg/database"
+	"goroutines/util"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableDB points at a closed port, pgxpool connects lazily so every flush fails fast
+func unreachableDB(t *testing.T) *database.DB {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), "postgres://postgres@127.0.0.1:1/goroutines?connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	return &database.DB{Pool: pool, QueryBuilder: &psql}
+}
+
+func TestBatchWriterAnswersEveryPersistAcrossCancel(t *testing.T) {
+	for range 20 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		writer := NewProductBatchWriter(unreachableDB(t), 4, time.Millisecond)
+		writer.Start(ctx)
+
+		const n = 200
+		var answered sync.WaitGroup
+		answered.Add(n)
+		for i := range n {
+			if i == n/2 {
+				cancel()
+			}
+			go func() {
+				defer answered.Done()
+				<-writer.Persist(context.Background(), &domain.Product{Name: "Shirt"})
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			answered.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("a Persist call was never answered")
+		}
+	}
+}
+
+func TestBatchWriterRejectsAfterStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := NewProductBatchWriter(unreachableDB(t), 4, time.Millisecond)
+	writer.Start(ctx)
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		result := <-writer.Persist(context.Background(), &domain.Product{})
+		return result == util.Result[*domain.Product]{Error: ErrBatchWriterClosed}
+	}, time.Second, time.Millisecond)
+}
+
+// Run against a migrated database, e.g.:
+//
+//	DB_HOST=localhost DB_PORT=5432 DB_USERNAME=postgres DB_PASSWORD=postgres DB_NAME=goroutines DB_PARAMS=sslmode=disable \
+//	go test -run=TestBatchWriterRejectsOnlyTheFailingRows ./internal/product/repository
+func TestBatchWriterRejectsOnlyTheFailingRows(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST not set, skipping database test")
+	}
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New(ctx, cfg.DB)
+	require.NoError(t, err)
+	// Closed after the cleanups deleting the written rows
+	t.Cleanup(db.Close)
+
+	// One batch of 6 rows, the sku of rows 1 and 4 is longer than its varchar(30) column
+	const n = 6
+	writer := NewProductBatchWriter(db, n, time.Second)
+	writer.Start(ctx)
+
+	results := make([]<-chan util.Result[*domain.Product], n)
+	for i := range results {
+		sku := fmt.Sprintf("batch-%d-%d", time.Now().UnixNano(), i)
+		if i == 1 || i == 4 {
+			sku = strings.Repeat("x", 31)
+		}
+		results[i] = writer.Persist(ctx, &domain.Product{
+			Name:      "Shirt",
+			Sku:       sku,
+			Category:  "Clothing",
+			Price:     10,
+			Stock:     1,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	for i, result := range results {
+		r := <-result
+		if i == 1 || i == 4 {
+			assert.Error(t, r.Error, "row %d", i)
+			continue
+		}
+
+		require.NoError(t, r.Error, "row %d", i)
+		assert.False(t, r.Result.Id.IsNil(), "row %d", i)
+		t.Cleanup(func() {
+			_, err := db.Pool.Exec(context.Background(), `DELETE FROM products WHERE id = $1`, r.Result.Id)
+			assert.NoError(t, err)
+		})
+	}
+}
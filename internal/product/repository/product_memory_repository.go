@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	domain "goroutines/internal/product"
+	"goroutines/pkg/database/memory"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ProductMemoryRepository is a thread-safe in-memory ProductRepository. Like the products
+// table it generates the id and created_at of new rows and rejects duplicate ids.
+type ProductMemoryRepository struct {
+	mu       sync.RWMutex
+	products map[uuid.UUID]domain.Product
+}
+
+var _ ProductRepository = (*ProductMemoryRepository)(nil)
+
+func NewProductMemoryRepository() *ProductMemoryRepository {
+	return &ProductMemoryRepository{
+		products: make(map[uuid.UUID]domain.Product),
+	}
+}
+
+// Persist stores a copy of p and fills its generated columns, undone if the transaction carried by ctx rolls back
+func (pr *ProductMemoryRepository) Persist(ctx context.Context, p *domain.Product) (*domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	row := *p
+	if row.Id.IsNil() {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		row.Id = id
+	}
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = time.Now()
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if _, ok := pr.products[row.Id]; ok {
+		return nil, memory.UniqueViolation("products", "products_pkey", fmt.Sprintf("Key (id)=(%s) already exists.", row.Id))
+	}
+	pr.products[row.Id] = row
+
+	memory.OnRollback(ctx, func() {
+		pr.mu.Lock()
+		defer pr.mu.Unlock()
+		delete(pr.products, row.Id)
+	})
+
+	*p = row
+	return p, nil
+}
+
+// GetReferenceById returns a copy of the product, nil when there is none
+func (pr *ProductMemoryRepository) GetReferenceById(ctx context.Context, id uuid.UUID) (*domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	p, ok := pr.products[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &p, nil
+}
+
+// All returns a copy of every product, oldest first
+func (pr *ProductMemoryRepository) All() []*domain.Product {
+	pr.mu.RLock()
+	products := make([]*domain.Product, 0, len(pr.products))
+	for _, p := range pr.products {
+		products = append(products, &p)
+	}
+	pr.mu.RUnlock()
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].CreatedAt.Before(products[j].CreatedAt)
+	})
+
+	return products
+}
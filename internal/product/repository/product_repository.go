@@ -5,6 +5,7 @@ import (
 	"errors"
 	domain "goroutines/internal/product"
 	"goroutines/pkg/database"
+	"goroutines/pkg/logging"
 	"log/slog"
 
 	sq "github.com/Masterminds/squirrel"
@@ -16,7 +17,6 @@ import (
 type ProductRepository interface {
 	GetReferenceById(ctx context.Context, id uuid.UUID) (*domain.Product, error)
 	Persist(ctx context.Context, p *domain.Product) (*domain.Product, error)
-	PersistTx(ctx context.Context, p *domain.Product, parentTx pgx.Tx) (*domain.Product, error)
 }
 
 type productRepository struct {
@@ -29,58 +29,30 @@ func NewProductRepository(db *database.DB) ProductRepository {
 	}
 }
 
-// CreateProduct creates a new product record in the database
+// Persist creates a new product record in the database, inside the unit of work carried by ctx if any
 func (pr *productRepository) Persist(ctx context.Context, p *domain.Product) (*domain.Product, error) {
 	db := pr.db
-	query := db.QueryBuilder.Insert("products").
-		Columns("name", "sku", "category", "image_url", "notes", "price", "stock", "location", "is_available", "created_at").
-		Values(
-			p.Name,
-			p.Sku,
-			p.Category,
-			p.ImageUrl,
-			p.Notes,
-			p.Price,
-			p.Stock,
-			p.Location,
-			p.IsAvailable,
-			p.CreatedAt,
-		).
-		Suffix("RETURNING id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at")
-
-	sql, args, err := query.ToSql()
+	sql, args, err := insertProductQuery(db, p)
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.Pool.QueryRow(ctx, sql, args...).Scan(
-		&p.Id,
-		&p.Name,
-		&p.Sku,
-		&p.Category,
-		&p.ImageUrl,
-		&p.Notes,
-		&p.Price,
-		&p.Stock,
-		&p.Location,
-		&p.IsAvailable,
-		&p.CreatedAt,
-	)
+	err = db.Querier(ctx).QueryRow(ctx, sql, args...).Scan(returningDest(p)...)
 	if err != nil {
 		if sqlErr := pr.db.ErrorCode(err); sqlErr != nil {
 			return nil, sqlErr
 		}
 
-		slog.Error("Cannot persist product on database", slog.Any("error", err))
+		logging.FromContext(ctx).Error("Cannot persist product on database", slog.Any("error", err))
 		return nil, err
 	}
 
 	return p, nil
 }
 
-func (pr *productRepository) PersistTx(ctx context.Context, p *domain.Product, parentTx pgx.Tx) (*domain.Product, error) {
-	db := pr.db
-	query := db.QueryBuilder.Insert("products").
+// insertProductQuery builds the INSERT ... RETURNING statement for a single product
+func insertProductQuery(db *database.DB, p *domain.Product) (string, []interface{}, error) {
+	return db.QueryBuilder.Insert("products").
 		Columns("name", "sku", "category", "image_url", "notes", "price", "stock", "location", "is_available", "created_at").
 		Values(
 			p.Name,
@@ -94,14 +66,13 @@ func (pr *productRepository) PersistTx(ctx context.Context, p *domain.Product, p
 			p.IsAvailable,
 			p.CreatedAt,
 		).
-		Suffix("RETURNING id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at")
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
+		Suffix("RETURNING id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at").
+		ToSql()
+}
 
-	err = parentTx.QueryRow(ctx, sql, args...).Scan(
+// returningDest lists the scan destinations matching the RETURNING clause of insertProductQuery
+func returningDest(p *domain.Product) []interface{} {
+	return []interface{}{
 		&p.Id,
 		&p.Name,
 		&p.Sku,
@@ -113,17 +84,7 @@ func (pr *productRepository) PersistTx(ctx context.Context, p *domain.Product, p
 		&p.Location,
 		&p.IsAvailable,
 		&p.CreatedAt,
-	)
-	if err != nil {
-		if sqlErr := pr.db.ErrorCode(err); sqlErr != nil {
-			return nil, sqlErr
-		}
-
-		slog.Error("Cannot persist product on database", slog.Any("error", err))
-		return nil, err
 	}
-
-	return p, nil
 }
 
 func (pr *productRepository) GetReferenceById(ctx context.Context, id uuid.UUID) (*domain.Product, error) {
@@ -142,7 +103,7 @@ func (pr *productRepository) GetReferenceById(ctx context.Context, id uuid.UUID)
 		return nil, err
 	}
 
-	rows, err := pr.db.Query(ctx, sql, args...)
+	rows, err := pr.db.Reader(ctx).Query(ctx, sql, args...)
 	if err == nil {
 		p, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[domain.Product])
 	}
@@ -150,7 +111,7 @@ func (pr *productRepository) GetReferenceById(ctx context.Context, id uuid.UUID)
 		return nil, nil
 	}
 	if err != nil {
-		slog.Error("cannot get product from database",
+		logging.FromContext(ctx).Error("cannot get product from database",
 			slog.Any("id", id),
 			slog.Any("error", err))
 		return nil, errors.New("cannot get product from database")
@@ -17,6 +17,10 @@ type ProductCreateRequest struct {
 	IsAvailable bool    `form:"isAvailable" binding:"required"`
 }
 
+type StrategyUpdateRequest struct {
+	Strategy string `json:"strategy" binding:"required"`
+}
+
 var ImageFormats = []string{".jpg", ".jpeg", ".png", ".webp"}
 
 func (pr *ProductCreateRequest) ValidateProductCreate() error {
@@ -29,6 +29,11 @@ type CreateProductResponse struct {
 	Data    ProductCreateResponse `json:"data"`
 }
 
+type StrategyShow struct {
+	Default   string   `json:"default"`
+	Available []string `json:"available"`
+}
+
 const ProductsCreateSuccMessage = "Successfully create products"
 
 func ProductToCreateResponse(data *product.Product) *CreateProductResponse {
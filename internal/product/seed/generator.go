@@ -0,0 +1,88 @@
+package seed
+
+import (
+	"fmt"
+	domain "goroutines/internal/product"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// epoch anchors the generated created_at, so a seed always yields the same rows
+var epoch = time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC)
+
+var (
+	adjectives = []string{
+		"Classic", "Everyday", "Premium", "Vintage", "Urban", "Organic", "Slim", "Heavy Duty",
+		"Limited", "Essential", "Compact", "Deluxe", "Sport", "Eco", "Signature", "Rustic",
+	}
+	// nouns per seeded category, other categories use fallbackNouns
+	nouns = map[string][]string{
+		"Clothing":    {"T-Shirt", "Hoodie", "Jacket", "Chinos", "Sweater", "Polo", "Jeans", "Cardigan"},
+		"Accessories": {"Watch", "Wallet", "Belt", "Backpack", "Scarf", "Sunglasses", "Cap", "Bracelet"},
+		"Footwear":    {"Sneakers", "Boots", "Loafers", "Sandals", "Runners", "Slip-Ons", "Oxfords"},
+		"Beverages":   {"Cold Brew", "Green Tea", "Lemonade", "Kombucha", "Espresso", "Cocoa", "Matcha"},
+	}
+	fallbackNouns = []string{"Item", "Kit", "Set", "Pack", "Bundle"}
+	locations     = []string{
+		"Jakarta", "Bandung", "Surabaya", "Medan", "Yogyakarta", "Semarang", "Makassar", "Denpasar",
+	}
+	notes = []string{
+		"Best seller of the season.",
+		"Ships within two business days.",
+		"Limited stock, restocked monthly.",
+		"Customer favourite with great reviews.",
+		"Imported, packed locally.",
+		"Eligible for free returns within 30 days.",
+	}
+	imageFormats = []string{".jpg", ".png", ".webp"}
+)
+
+// Generator derives products from a seed. Product i only depends on the seed and i,
+// so the dataset is the same whatever the number of workers writing it.
+type Generator struct {
+	seed       uint64
+	categories []string
+}
+
+func NewGenerator(seed uint64, categories []string) *Generator {
+	return &Generator{seed, categories}
+}
+
+// Product returns the i-th product of the dataset
+func (g *Generator) Product(i int) *domain.Product {
+	r := rand.New(rand.NewPCG(g.seed, uint64(i)))
+
+	category := pick(r, g.categories)
+	kinds, ok := nouns[category]
+	if !ok {
+		kinds = fallbackNouns
+	}
+	name := pick(r, adjectives) + " " + pick(r, kinds)
+	sku := fmt.Sprintf("SKU-%d-%08d", g.seed%1000, i)
+
+	// Log-normal prices: mostly cheap, a long tail of expensive items
+	price := math.Round(math.Exp(r.NormFloat64()*0.8+10.5)) + 1
+	stock := 0
+	if r.Float64() > 0.1 {
+		stock = r.IntN(1000) + 1
+	}
+
+	return &domain.Product{
+		Name:        name,
+		Sku:         sku,
+		Category:    category,
+		ImageUrl:    "https://picsum.photos/seed/" + strings.ToLower(sku) + "/400" + pick(r, imageFormats),
+		Notes:       pick(r, notes),
+		Price:       price,
+		Stock:       stock,
+		Location:    pick(r, locations),
+		IsAvailable: stock > 0,
+		CreatedAt:   epoch.Add(time.Duration(r.Int64N(int64(365 * 24 * time.Hour)))),
+	}
+}
+
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.IntN(len(values))]
+}
@@ -0,0 +1,26 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratorDeterministic(t *testing.T) {
+	categories := []string{"Accessories", "Beverages", "Clothing", "Footwear"}
+	a := NewGenerator(42, categories)
+	b := NewGenerator(42, categories)
+
+	for i := range 100 {
+		p := a.Product(i)
+		assert.Equal(t, p, b.Product(i))
+
+		assert.Contains(t, categories, p.Category)
+		assert.LessOrEqual(t, len(p.Name), 30)
+		assert.LessOrEqual(t, len(p.Sku), 30)
+		assert.GreaterOrEqual(t, p.Price, 1.0)
+		assert.Equal(t, p.Stock > 0, p.IsAvailable)
+	}
+
+	assert.NotEqual(t, a.Product(0), NewGenerator(43, categories).Product(0))
+}
@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goroutines/pkg/database"
+	"goroutines/util/conc"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNoCategories = errors.New("no category to seed products into, run the migrations first")
+
+var productColumns = []string{
+	"name", "sku", "category", "image_url", "notes", "price", "stock", "location", "is_available", "created_at",
+}
+
+// Options configures Run
+type Options struct {
+	// Count is the number of products to write
+	Count int
+	// Seed makes the generated dataset reproducible
+	Seed uint64
+	// Workers is the number of goroutines writing batches concurrently
+	Workers int
+	// BatchSize is the number of products written per transaction
+	BatchSize int
+	// Truncate empties the products table first
+	Truncate bool
+	// Progress is called after every committed batch with the products written so far
+	Progress func(done, total int)
+}
+
+// Run writes opts.Count generated products across the existing categories. Each batch is
+// copied in its own transaction, so a failed batch leaves the committed ones in place.
+func Run(ctx context.Context, db *database.DB, opts Options) error {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 1
+	}
+
+	if opts.Truncate {
+		if _, err := db.Exec(ctx, `TRUNCATE TABLE products`); err != nil {
+			return fmt.Errorf("truncate products: %w", err)
+		}
+	}
+
+	categories, err := categoryNames(ctx, db)
+	if err != nil {
+		return err
+	}
+	gen := NewGenerator(opts.Seed, categories)
+
+	// Batch start offsets, the first failing batch cancels the ones not started yet
+	var starts []int
+	for start := 0; start < opts.Count; start += opts.BatchSize {
+		starts = append(starts, start)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var done atomic.Int64
+
+	results := conc.Map(ctx, starts, opts.Workers, func(ctx context.Context, start int) (struct{}, error) {
+		end := min(start+opts.BatchSize, opts.Count)
+		if err := writeBatch(ctx, db, gen, start, end); err != nil {
+			cancel()
+			return struct{}{}, fmt.Errorf("seed products %d-%d: %w", start, end-1, err)
+		}
+
+		n := done.Add(int64(end - start))
+		if opts.Progress != nil {
+			opts.Progress(int(n), opts.Count)
+		}
+		return struct{}{}, nil
+	})
+
+	// Report the failure that canceled the rest rather than their cancellation
+	var firstErr error
+	for _, r := range results {
+		if r.Error == nil {
+			continue
+		}
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(r.Error, context.Canceled)) {
+			firstErr = r.Error
+		}
+	}
+
+	return firstErr
+}
+
+func writeBatch(ctx context.Context, db *database.DB, gen *Generator, start, end int) error {
+	rows := make([][]any, 0, end-start)
+	for i := start; i < end; i++ {
+		p := gen.Product(i)
+		rows = append(rows, []any{
+			p.Name, p.Sku, p.Category, p.ImageUrl, p.Notes, p.Price, p.Stock, p.Location, p.IsAvailable, p.CreatedAt,
+		})
+	}
+
+	return db.BeginTransaction(ctx, func(tx pgx.Tx, ctx context.Context) error {
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"products"}, productColumns, pgx.CopyFromRows(rows))
+		return err
+	})
+}
+
+func categoryNames(ctx context.Context, db *database.DB) ([]string, error) {
+	rows, err := db.Query(ctx, `SELECT name FROM categories WHERE deleted_at IS NULL ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, ErrNoCategories
+	}
+
+	return names, nil
+}
@@ -2,192 +2,215 @@ package service
 
 import (
 	"context"
+	"goroutines/internal/category"
 	categoryRepository "goroutines/internal/category/repository"
 	"goroutines/internal/product"
 	"goroutines/internal/product/errs"
 	"goroutines/internal/product/repository"
 	"goroutines/internal/product/request"
 	"goroutines/pkg/database"
+	"goroutines/pkg/logging"
+	"goroutines/pkg/tracing"
 	"goroutines/util"
+	"goroutines/util/conc"
+	"log/slog"
 	"runtime"
 
 	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ProductService interface {
-	CreateProduct(p *request.ProductCreateRequest) (*product.Product, error)
-	CreateProductGoroutines(p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
-	CreateProductGoroutinesBuffered(p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
-	CreateProductTx(p *request.ProductCreateRequest) (*product.Product, error)
+	CreateProduct(ctx context.Context, p *request.ProductCreateRequest) (*product.Product, error)
+	CreateProductGoroutines(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
+	CreateProductGoroutinesBuffered(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
+	CreateProductTx(ctx context.Context, p *request.ProductCreateRequest) (*product.Product, error)
+	CreateProductBatched(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
 }
 
 type ProductDependency struct {
 	Product  repository.ProductRepository
 	Category categoryRepository.CategoryRepository
+
+	// Batch is optional, CreateProductBatched fails without it
+	Batch *repository.ProductBatchWriter
 }
 
 type productService struct {
-	db   *database.DB
+	tx   database.TxManager
 	repo *ProductDependency
 	ctx  context.Context
+
+	// Concurrent creates mostly share a handful of categories
+	categories conc.Singleflight[string, *category.Category]
+	// workers bounds the creates of the buffered goroutines strategy to one per CPU
+	workers *conc.Semaphore
 }
 
 func NewProductService(
-	db *database.DB,
+	tx database.TxManager,
 	repo *ProductDependency,
 	ctx context.Context,
 ) ProductService {
 	return &productService{
-		db:   db,
-		repo: repo,
-		ctx:  ctx,
+		tx:      tx,
+		repo:    repo,
+		ctx:     ctx,
+		workers: conc.NewSemaphore(int64(runtime.NumCPU())),
 	}
 }
 
-func (svc *productService) CreateProduct(p *request.ProductCreateRequest) (*product.Product, error) {
-	repo := svc.repo
+func (svc *productService) CreateProduct(ctx context.Context, p *request.ProductCreateRequest) (*product.Product, error) {
+	ctx, span := svc.start(ctx, "CreateProduct")
+	productCreated, err := svc.create(ctx, p)
+	tracing.End(span, err)
 
-	categoryFound, err := repo.Category.GetReferenceByName(svc.ctx, p.Category)
-	if err != nil {
-		return nil, errs.ProductErrsCategoryNotFound
-	}
+	return productCreated, err
+}
 
-	model := &product.Product{
-		Name:        p.Name,
-		Sku:         p.Sku,
-		Category:    categoryFound.Name,
-		ImageUrl:    p.ImageUrl,
-		Notes:       p.Notes,
-		Price:       p.Price,
-		Stock:       *p.Stock,
-		Location:    p.Location,
-		IsAvailable: p.IsAvailable,
-	}
-	productPersisted, err := repo.Product.Persist(svc.ctx, model)
-	if err != nil {
-		return nil, err
-	}
+func (svc *productService) CreateProductGoroutines(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
+	ctx, span := svc.start(ctx, "CreateProductGoroutines")
 
-	return productPersisted, nil
-}
+	// The span travels with ctx, so the spans of the goroutine stay under the request
+	return util.Go(func() (productCreated *product.Product, err error) {
+		defer func() { tracing.End(span, err) }()
 
-func (svc *productService) CreateProductGoroutines(p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
-	repo := svc.repo
+		return svc.create(ctx, p)
+	})
+}
 
-	result := make(chan util.Result[*product.Product])
-	go func() {
-		categoryFound, err := repo.Category.GetReferenceByName(svc.ctx, p.Category)
-		if err != nil {
-			result <- util.Result[*product.Product]{
-				Error: errs.ProductErrsCategoryNotFound,
-			}
-			return
-		}
+func (svc *productService) CreateProductGoroutinesBuffered(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
+	ctx, span := svc.start(ctx, "CreateProductGoroutinesBuffered")
 
-		model := &product.Product{
-			Name:        p.Name,
-			Sku:         p.Sku,
-			Category:    categoryFound.Name,
-			ImageUrl:    p.ImageUrl,
-			Notes:       p.Notes,
-			Price:       p.Price,
-			Stock:       *p.Stock,
-			Location:    p.Location,
-			IsAvailable: p.IsAvailable,
-		}
-		productPersisted, err := repo.Product.Persist(svc.ctx, model)
-		if err != nil {
-			result <- util.Result[*product.Product]{
-				Error: err,
-			}
-			return
-		}
+	return util.Go(func() (productCreated *product.Product, err error) {
+		defer func() { tracing.End(span, err) }()
 
-		result <- util.Result[*product.Product]{
-			Result: productPersisted,
+		// Creates beyond the worker slots queue here instead of piling up on the pool
+		if err := svc.workers.Acquire(ctx, 1); err != nil {
+			return nil, err
 		}
-		close(result)
-	}()
+		defer svc.workers.Release(1)
 
-	return result
+		return svc.create(ctx, p)
+	})
 }
 
-func (svc *productService) CreateProductGoroutinesBuffered(p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
+func (svc *productService) CreateProductTx(ctx context.Context, p *request.ProductCreateRequest) (*product.Product, error) {
+	ctx, span := svc.start(ctx, "CreateProductTx")
 	repo := svc.repo
 
-	worker := runtime.NumCPU()
-	result := make(chan util.Result[*product.Product], worker)
-
-	task := func() {
-		defer close(result)
-
-		categoryFound, err := repo.Category.GetReferenceByName(svc.ctx, p.Category)
+	var result *product.Product
+	err := svc.tx.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+		// ctx carries the transaction, both repositories join it
+		categoryFound, err := repo.Category.GetReferenceByName(ctx, p.Category)
 		if err != nil {
-			result <- util.Result[*product.Product]{
-				Error: errs.ProductErrsCategoryNotFound,
-			}
-			return
+			return errs.ProductErrsCategoryNotFound
 		}
 
-		model := &product.Product{
-			Name:        p.Name,
-			Sku:         p.Sku,
-			Category:    categoryFound.Name,
-			ImageUrl:    p.ImageUrl,
-			Notes:       p.Notes,
-			Price:       p.Price,
-			Stock:       *p.Stock,
-			Location:    p.Location,
-			IsAvailable: p.IsAvailable,
-		}
-		productPersisted, err := repo.Product.Persist(svc.ctx, model)
+		productPersisted, err := repo.Product.Persist(ctx, newModel(p, categoryFound))
 		if err != nil {
-			result <- util.Result[*product.Product]{
-				Error: err,
-			}
-			return
+			return err
 		}
 
-		result <- util.Result[*product.Product]{
-			Result: productPersisted,
-		}
+		result = productPersisted
+		return nil
+	})
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
 	}
-	go task()
 
-	return result
+	return result, nil
 }
 
-func (svc *productService) CreateProductTx(p *request.ProductCreateRequest) (*product.Product, error) {
+func (svc *productService) CreateProductBatched(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
+	ctx, span := svc.start(ctx, "CreateProductBatched")
 	repo := svc.repo
 
-	var result *product.Product
-	if err := svc.db.BeginTransaction(svc.ctx, func(tx pgx.Tx, ctx context.Context) error {
-		categoryFound, err := repo.Category.GetReferenceByName(ctx, p.Category)
-		if err != nil {
-			return errs.ProductErrsCategoryNotFound
-		}
+	return util.Go(func() (productCreated *product.Product, err error) {
+		defer func() { tracing.End(span, err) }()
 
-		product := &product.Product{
-			Name:        p.Name,
-			Sku:         p.Sku,
-			Category:    categoryFound.Name,
-			ImageUrl:    p.ImageUrl,
-			Notes:       p.Notes,
-			Price:       p.Price,
-			Stock:       *p.Stock,
-			Location:    p.Location,
-			IsAvailable: p.IsAvailable,
+		if repo.Batch == nil {
+			return nil, errs.ProductErrsBatchDisabled
 		}
-		productPersisted, err := repo.Product.PersistTx(ctx, product, tx)
+
+		categoryFound, err := svc.findCategory(ctx, p.Category)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		result = productPersisted
-		return nil
-	}); err != nil {
+		// The writer answers each caller on its own channel, its flush span links to this one
+		persisted := <-repo.Batch.Persist(ctx, newModel(p, categoryFound))
+		return persisted.Result, persisted.Error
+	})
+}
+
+// start opens the span of a service method on the service context, under the span of the caller,
+// and carries the request logger over. The service context outlives the request, so a client going
+// away doesn't abort a create.
+func (svc *productService) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	svcCtx := trace.ContextWithSpan(svc.ctx, trace.SpanFromContext(ctx))
+	if logger, ok := logging.Lookup(ctx); ok {
+		svcCtx = logging.WithLogger(svcCtx, logger)
+	}
+
+	return tracing.Tracer().Start(svcCtx, "ProductService."+method)
+}
+
+// create is the shared path of the strategies that persist straight to the pool
+func (svc *productService) create(ctx context.Context, p *request.ProductCreateRequest) (productCreated *product.Product, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ProductService.create")
+	defer func() { tracing.End(span, err) }()
+
+	// Runs on a service goroutine for the goroutine strategies, still logging with the request ID
+	logger := logging.FromContext(ctx)
+	categoryFound, err := svc.findCategory(ctx, p.Category)
+	if err != nil {
+		logger.Debug("Product category lookup failed", slog.String("category", p.Category), slog.Any("error", err))
 		return nil, err
 	}
 
-	return result, nil
+	productCreated, err = svc.repo.Product.Persist(ctx, newModel(p, categoryFound))
+	if err != nil {
+		logger.Debug("Product persist failed", slog.Any("error", err))
+		return nil, err
+	}
+	logger.Debug("Product created", slog.String("id", productCreated.Id.String()))
+
+	return productCreated, nil
+}
+
+// findCategory collapses concurrent lookups of the same category into one query.
+// The lookup runs detached from the caller, so it must not be used inside a transaction.
+func (svc *productService) findCategory(ctx context.Context, name string) (*category.Category, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ProductService.findCategory")
+	defer span.End()
+
+	found, shared := svc.categories.Do(ctx, name, func(ctx context.Context) (*category.Category, error) {
+		return svc.repo.Category.GetReferenceByName(ctx, name)
+	})
+	// A shared lookup ran under the span of the caller that started it
+	span.SetAttributes(attribute.Bool("singleflight.shared", shared))
+	if found.Error != nil {
+		span.RecordError(found.Error)
+		return nil, errs.ProductErrsCategoryNotFound
+	}
+
+	return found.Result, nil
+}
+
+// newModel maps the create request onto the product entity
+func newModel(p *request.ProductCreateRequest, c *category.Category) *product.Product {
+	return &product.Product{
+		Name:        p.Name,
+		Sku:         p.Sku,
+		Category:    c.Name,
+		ImageUrl:    p.ImageUrl,
+		Notes:       p.Notes,
+		Price:       p.Price,
+		Stock:       *p.Stock,
+		Location:    p.Location,
+		IsAvailable: p.IsAvailable,
+	}
 }
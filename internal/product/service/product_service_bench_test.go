@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goroutines/config"
+	categoryRepository "goroutines/internal/category/repository"
+	"goroutines/internal/product/repository"
+	"goroutines/internal/product/request"
+	"goroutines/pkg/database"
+	"os"
+	"testing"
+)
+
+// Run against a migrated database, e.g.:
+//
+//	DB_HOST=localhost DB_PORT=5432 DB_USERNAME=postgres DB_PASSWORD=postgres DB_NAME=goroutines DB_PARAMS=sslmode=disable \
+//	go test -run=^$ -bench=BenchmarkCreateProduct -cpu=1,8,64 ./internal/product/service
+func BenchmarkCreateProduct(b *testing.B) {
+	if os.Getenv("DB_HOST") == "" {
+		b.Skip("DB_HOST not set, skipping database benchmark")
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := database.New(ctx, cfg.DB)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	batchWriter := repository.NewProductBatchWriter(db, repository.DefaultBatchMaxRows, repository.DefaultBatchMaxDelay)
+	batchWriter.Start(ctx)
+
+	svc := NewProductService(db, &ProductDependency{
+		Product:  repository.NewProductRepository(db),
+		Category: categoryRepository.NewCategoryRepository(db),
+		Batch:    batchWriter,
+	}, ctx)
+
+	strategies := map[string]func(p *request.ProductCreateRequest) error{
+		"sync": func(p *request.ProductCreateRequest) error {
+			_, err := svc.CreateProduct(ctx, p)
+			return err
+		},
+		"goroutines": func(p *request.ProductCreateRequest) error {
+			return (<-svc.CreateProductGoroutines(ctx, p)).Error
+		},
+		"buffered": func(p *request.ProductCreateRequest) error {
+			return (<-svc.CreateProductGoroutinesBuffered(ctx, p)).Error
+		},
+		"tx": func(p *request.ProductCreateRequest) error {
+			_, err := svc.CreateProductTx(ctx, p)
+			return err
+		},
+		"batched": func(p *request.ProductCreateRequest) error {
+			return (<-svc.CreateProductBatched(ctx, p)).Error
+		},
+	}
+
+	for _, name := range []string{"sync", "goroutines", "buffered", "tx", "batched"} {
+		create := strategies[name]
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				stock := 10
+				i := 0
+				for pb.Next() {
+					i++
+					if err := create(&request.ProductCreateRequest{
+						Name:        "Bench product",
+						Sku:         fmt.Sprintf("bench-%s-%d", name, i),
+						Category:    "Clothing",
+						ImageUrl:    "https://example.com/image.png",
+						Notes:       "benchmark",
+						Price:       100,
+						Stock:       &stock,
+						Location:    "Warehouse",
+						IsAvailable: true,
+					}); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
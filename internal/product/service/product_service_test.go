@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goroutines/internal/category"
+	categoryErrs "goroutines/internal/category/errs"
+	categoryRepository "goroutines/internal/category/repository"
+	"goroutines/internal/product"
+	"goroutines/internal/product/errs"
+	"goroutines/internal/product/repository"
+	"goroutines/internal/product/request"
+	dbErrs "goroutines/pkg/database/errs"
+	"goroutines/pkg/database/memory"
+	"goroutines/pkg/logging"
+	"goroutines/util"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var errPersistFailed = errors.New("persist failed")
+
+// failAfterPersist stores the product then fails, like a statement failing after an insert
+type failAfterPersist struct {
+	repository.ProductRepository
+}
+
+func (r failAfterPersist) Persist(ctx context.Context, p *product.Product) (*product.Product, error) {
+	if _, err := r.ProductRepository.Persist(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return nil, errPersistFailed
+}
+
+func newMemoryService(t *testing.T, failPersist bool) (ProductService, *repository.ProductMemoryRepository) {
+	t.Helper()
+
+	categories := categoryRepository.NewCategoryMemoryRepository()
+	_, err := categories.Persist(context.Background(), &category.Category{Name: "Clothing"})
+	require.NoError(t, err)
+
+	products := repository.NewProductMemoryRepository()
+	var productRepo repository.ProductRepository = products
+	if failPersist {
+		productRepo = failAfterPersist{products}
+	}
+
+	svc := NewProductService(memory.NewTxManager(), &ProductDependency{
+		Product:  productRepo,
+		Category: categories,
+	}, context.Background())
+
+	return svc, products
+}
+
+func newCreateRequest(category string) *request.ProductCreateRequest {
+	stock := 10
+	return &request.ProductCreateRequest{
+		Name:        "Classic T-Shirt",
+		Sku:         "SKU-1",
+		Category:    category,
+		ImageUrl:    "https://example.com/t-shirt.jpg",
+		Notes:       "Best seller",
+		Price:       100,
+		Stock:       &stock,
+		Location:    "Jakarta",
+		IsAvailable: true,
+	}
+}
+
+func TestCreateStrategies(t *testing.T) {
+	strategies := []string{StrategySync, StrategyGoroutines, StrategyGoroutinesBuffered, StrategyTx}
+
+	tests := []struct {
+		name        string
+		category    string
+		failPersist bool
+		wantErr     error
+		// wantRows per strategy, 1 when absent
+		wantRows map[string]int
+	}{
+		{
+			name:     "creates the product",
+			category: "Clothing",
+		},
+		{
+			name:     "unknown category",
+			category: "Groceries",
+			wantErr:  errs.ProductErrsCategoryNotFound,
+			wantRows: map[string]int{StrategySync: 0, StrategyGoroutines: 0, StrategyGoroutinesBuffered: 0, StrategyTx: 0},
+		},
+		{
+			name:        "failure after the insert is only rolled back in a transaction",
+			category:    "Clothing",
+			failPersist: true,
+			wantErr:     errPersistFailed,
+			wantRows:    map[string]int{StrategyTx: 0},
+		},
+	}
+
+	for _, strategy := range strategies {
+		for _, tt := range tests {
+			t.Run(strategy+"/"+tt.name, func(t *testing.T) {
+				svc, products := newMemoryService(t, tt.failPersist)
+				registry, err := NewStrategyRegistry(svc, strategy)
+				require.NoError(t, err)
+				_, create, err := registry.Resolve("")
+				require.NoError(t, err)
+
+				created := <-create(context.Background(), newCreateRequest(tt.category))
+
+				wantRows, ok := tt.wantRows[strategy]
+				if !ok {
+					wantRows = 1
+				}
+				assert.Len(t, products.All(), wantRows)
+
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, created.Error, tt.wantErr)
+					assert.Nil(t, created.Result)
+					return
+				}
+
+				require.NoError(t, created.Error)
+				assert.False(t, created.Result.Id.IsNil())
+				assert.False(t, created.Result.CreatedAt.IsZero())
+				assert.Equal(t, tt.category, created.Result.Category)
+
+				stored, err := products.GetReferenceById(context.Background(), created.Result.Id)
+				require.NoError(t, err)
+				assert.Equal(t, created.Result, stored)
+			})
+		}
+	}
+}
+
+func TestCreateBatchedWithoutWriter(t *testing.T) {
+	svc, products := newMemoryService(t, false)
+
+	created := <-svc.CreateProductBatched(context.Background(), newCreateRequest("Clothing"))
+
+	assert.ErrorIs(t, created.Error, errs.ProductErrsBatchDisabled)
+	assert.Empty(t, products.All())
+}
+
+func TestCreateConcurrently(t *testing.T) {
+	svc, products := newMemoryService(t, false)
+
+	const n = 100
+	ids := sync.Map{}
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created := <-svc.CreateProductGoroutines(context.Background(), newCreateRequest("Clothing"))
+			if assert.NoError(t, created.Error) {
+				_, dup := ids.LoadOrStore(created.Result.Id, true)
+				assert.False(t, dup, "duplicate id %s", created.Result.Id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, products.All(), n)
+}
+
+// peakPersist records the most Persist calls running at once
+type peakPersist struct {
+	repository.ProductRepository
+	inFlight, peak atomic.Int32
+}
+
+func (r *peakPersist) Persist(ctx context.Context, p *product.Product) (*product.Product, error) {
+	cur := r.inFlight.Add(1)
+	defer r.inFlight.Add(-1)
+	for old := r.peak.Load(); cur > old && !r.peak.CompareAndSwap(old, cur); old = r.peak.Load() {
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	return r.ProductRepository.Persist(ctx, p)
+}
+
+func TestCreateBufferedIsBoundedPerCPU(t *testing.T) {
+	svc, products := newMemoryService(t, false)
+	peak := &peakPersist{ProductRepository: products}
+	svc.(*productService).repo.Product = peak
+
+	const n = 64
+	results := make([]<-chan util.Result[*product.Product], n)
+	for i := range results {
+		results[i] = svc.CreateProductGoroutinesBuffered(context.Background(), newCreateRequest("Clothing"))
+	}
+	for _, result := range results {
+		assert.NoError(t, (<-result).Error)
+	}
+
+	assert.Len(t, products.All(), n)
+	assert.LessOrEqual(t, int(peak.peak.Load()), runtime.NumCPU())
+}
+
+func TestMemoryRepositoryConstraints(t *testing.T) {
+	ctx := context.Background()
+
+	products := repository.NewProductMemoryRepository()
+	p, err := products.Persist(ctx, &product.Product{Name: "a"})
+	require.NoError(t, err)
+
+	_, err = products.Persist(ctx, &product.Product{Id: p.Id, Name: "b"})
+	assert.ErrorIs(t, err, dbErrs.ErrUniqueViolation)
+
+	missing, err := products.GetReferenceById(ctx, [16]byte{1})
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	categories := categoryRepository.NewCategoryMemoryRepository()
+	_, err = categories.Persist(ctx, &category.Category{Name: "Clothing"})
+	require.NoError(t, err)
+	dbErrs.RegisterConstraints(categoryErrs.Constraints)
+	_, err = categories.Persist(ctx, &category.Category{Name: "Clothing"})
+	assert.ErrorIs(t, err, categoryErrs.CategoryErrsAlreadyExists)
+	assert.ErrorIs(t, err, dbErrs.ErrUniqueViolation)
+
+	_, err = categories.GetReferenceByName(ctx, "Groceries")
+	assert.Error(t, err)
+}
+
+func TestCreateGoroutinesSpansStayInTheRequestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	svc, _ := newMemoryService(t, false)
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	created := <-svc.CreateProductGoroutines(ctx, newCreateRequest("Clothing"))
+	request.End()
+	require.NoError(t, created.Error)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, request.SpanContext().TraceID(), span.SpanContext().TraceID(), span.Name())
+		spans[span.Name()] = span
+	}
+
+	// request > CreateProductGoroutines > create (on the spawned goroutine) > findCategory
+	parents := map[string]string{
+		"ProductService.CreateProductGoroutines": "request",
+		"ProductService.create":                  "ProductService.CreateProductGoroutines",
+		"ProductService.findCategory":            "ProductService.create",
+	}
+	for name, parent := range parents {
+		require.Contains(t, spans, name)
+		require.Contains(t, spans, parent)
+		assert.Equal(t, spans[parent].SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+}
+
+func TestCreateGoroutinesLogsWithTheRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).
+		With(slog.String(logging.RequestIDKey, "req-7"))
+
+	svc, _ := newMemoryService(t, false)
+	ctx := logging.WithLogger(context.Background(), logger)
+	created := <-svc.CreateProductGoroutines(ctx, newCreateRequest("Groceries"))
+	require.Error(t, created.Error)
+
+	assert.Contains(t, buf.String(), "Product category lookup failed")
+	assert.Contains(t, buf.String(), "request_id=req-7")
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"goroutines/internal/product"
+	"goroutines/internal/product/errs"
+	"goroutines/internal/product/request"
+	"goroutines/util"
+	"sort"
+	"sync"
+)
+
+// Create strategy names, selectable per request or as the registry default
+const (
+	StrategySync               = "sync"
+	StrategyGoroutines         = "goroutines"
+	StrategyGoroutinesBuffered = "goroutines-buffered"
+	StrategyTx                 = "tx"
+	StrategyBatched            = "batched"
+)
+
+// CreateStrategy creates a product and delivers the outcome on the returned channel
+type CreateStrategy func(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product]
+
+// StrategyRegistry holds the create strategies keyed by name and the default one.
+// The default can be changed at runtime, so every access is guarded.
+type StrategyRegistry struct {
+	mu         sync.RWMutex
+	strategies map[string]CreateStrategy
+	current    string
+}
+
+// NewStrategyRegistry registers every ProductService create method under its strategy name
+func NewStrategyRegistry(svc ProductService, defaultName string) (*StrategyRegistry, error) {
+	r := &StrategyRegistry{
+		strategies: map[string]CreateStrategy{
+			StrategySync:               fromSync(svc.CreateProduct),
+			StrategyGoroutines:         svc.CreateProductGoroutines,
+			StrategyGoroutinesBuffered: svc.CreateProductGoroutinesBuffered,
+			StrategyTx:                 fromSync(svc.CreateProductTx),
+			StrategyBatched:            svc.CreateProductBatched,
+		},
+	}
+
+	if err := r.SetDefault(defaultName); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Resolve returns the strategy registered under name, or the default one when name is empty
+func (r *StrategyRegistry) Resolve(name string) (string, CreateStrategy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.current
+	}
+
+	strategy, ok := r.strategies[name]
+	if !ok {
+		return "", nil, errs.ProductErrsStrategyUnknown
+	}
+
+	return name, strategy, nil
+}
+
+// Default returns the name of the strategy used when a request does not pick one
+func (r *StrategyRegistry) Default() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current
+}
+
+// SetDefault switches the default strategy
+func (r *StrategyRegistry) SetDefault(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.strategies[name]; !ok {
+		return errs.ProductErrsStrategyUnknown
+	}
+	r.current = name
+
+	return nil
+}
+
+// Names returns the registered strategy names in sorted order
+func (r *StrategyRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.strategies))
+	for name := range r.strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// fromSync adapts a blocking create method to the CreateStrategy signature
+func fromSync(create func(ctx context.Context, p *request.ProductCreateRequest) (*product.Product, error)) CreateStrategy {
+	return func(ctx context.Context, p *request.ProductCreateRequest) <-chan util.Result[*product.Product] {
+		result := make(chan util.Result[*product.Product], 1)
+
+		productCreated, err := create(ctx, p)
+		result <- util.Result[*product.Product]{
+			Result: productCreated,
+			Error:  err,
+		}
+		close(result)
+
+		return result
+	}
+}
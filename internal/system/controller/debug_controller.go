@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"goroutines/internal/system/errs"
+	"goroutines/pkg/stackdump"
+	"net/http"
+	"runtime/trace"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTraceSeconds bounds a runtime trace capture, tracing slows every goroutine down
+const maxTraceSeconds = 60
+
+type DebugController interface {
+	Trace(ctx *gin.Context)
+	Goroutines(ctx *gin.Context)
+}
+
+type debugController struct{}
+
+func NewDebugController() DebugController {
+	return &debugController{}
+}
+
+// Trace captures a runtime trace for ?seconds=N (default 5), to open with go tool trace.
+// A single trace runs at a time.
+func (c *debugController) Trace(ctx *gin.Context) {
+	seconds, err := strconv.Atoi(ctx.DefaultQuery("seconds", "5"))
+	if err != nil || seconds < 1 || seconds > maxTraceSeconds {
+		ctx.Error(errs.SystemErrsInvalidTrace)
+		return
+	}
+
+	// The trace streams as soon as it starts, headers go first
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Content-Disposition", `attachment; filename="trace.out"`)
+	if err := trace.Start(ctx.Writer); err != nil {
+		header.Del("Content-Type")
+		header.Del("Content-Disposition")
+		ctx.Error(errs.SystemErrsTraceRunning)
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Request.Context().Done():
+	}
+	trace.Stop()
+}
+
+// Goroutines dumps every goroutine grouped by stack with counts, ?format=text for plain text
+func (c *debugController) Goroutines(ctx *gin.Context) {
+	dump := stackdump.Capture()
+
+	if ctx.Query("format") == "text" {
+		ctx.Header("Content-Type", "text/plain; charset=utf-8")
+		ctx.Status(http.StatusOK)
+		dump.WriteText(ctx.Writer)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dump)
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"goroutines/pkg/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController interface {
+	Live(ctx *gin.Context)
+	Ready(ctx *gin.Context)
+}
+
+type healthController struct {
+	health *health.Health
+}
+
+func NewHealthController(h *health.Health) HealthController {
+	return &healthController{h}
+}
+
+// Live answers as long as the process serves requests
+func (c *healthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready runs the readiness checks, 503 with the breakdown when a required one fails
+func (c *healthController) Ready(ctx *gin.Context) {
+	report := c.health.Ready(ctx.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"goroutines/internal/system/errs"
+	"goroutines/internal/system/response"
+	"goroutines/pkg/database"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SystemController interface {
+	PoolStats(ctx *gin.Context)
+	TopQueries(ctx *gin.Context)
+}
+
+type systemController struct {
+	// db is nil with the memory storage
+	db *database.DB
+}
+
+func NewSystemController(db *database.DB) SystemController {
+	return &systemController{db}
+}
+
+func (c *systemController) PoolStats(ctx *gin.Context) {
+	if c.db == nil {
+		ctx.Error(errs.SystemErrsNoDatabase)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response.PoolStatsToShow(c.db.PoolStats()))
+}
+
+// TopQueries serves the query analytics, ?limit=N (default 10) and ?sort=total|p99 (default total)
+func (c *systemController) TopQueries(ctx *gin.Context) {
+	if c.db == nil {
+		ctx.Error(errs.SystemErrsNoDatabase)
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		ctx.Error(errs.SystemErrsInvalidLimit)
+		return
+	}
+
+	by := ctx.DefaultQuery("sort", database.SortByTotal)
+	if by != database.SortByTotal && by != database.SortByP99 {
+		ctx.Error(errs.SystemErrsInvalidSort)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response.QueryStatsToShow(c.db.TopQueries(limit, by)))
+}
@@ -0,0 +1,11 @@
+package errs
+
+import "errors"
+
+var (
+	SystemErrsInvalidLimit = errors.New("Limit must be a positive integer")
+	SystemErrsInvalidSort  = errors.New("Sort must be total or p99")
+	SystemErrsNoDatabase   = errors.New("Database statistics are unavailable with the memory storage")
+	SystemErrsInvalidTrace = errors.New("Seconds must be an integer between 1 and 60")
+	SystemErrsTraceRunning = errors.New("A runtime trace is already being captured")
+)
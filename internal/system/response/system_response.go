@@ -0,0 +1,78 @@
+package response
+
+import (
+	"encoding/json"
+	"goroutines/pkg/database"
+	"time"
+)
+
+type PoolStatShow struct {
+	Name              string  `json:"name"`
+	Acquired          int32   `json:"acquired"`
+	Idle              int32   `json:"idle"`
+	Constructing      int32   `json:"constructing"`
+	Total             int32   `json:"total"`
+	Max               int32   `json:"max"`
+	AcquireCount      int64   `json:"acquireCount"`
+	WaitCount         int64   `json:"waitCount"`
+	AcquireDurationMs float64 `json:"acquireDurationMs"`
+	CanceledAcquires  int64   `json:"canceledAcquires"`
+}
+
+func PoolStatsToShow(stats []database.PoolStat) []PoolStatShow {
+	shows := make([]PoolStatShow, 0, len(stats))
+	for _, s := range stats {
+		shows = append(shows, PoolStatShow{
+			Name:              s.Name,
+			Acquired:          s.Acquired,
+			Idle:              s.Idle,
+			Constructing:      s.Constructing,
+			Total:             s.Total,
+			Max:               s.Max,
+			AcquireCount:      s.AcquireCount,
+			WaitCount:         s.WaitCount,
+			AcquireDurationMs: ms(s.AcquireDuration),
+			CanceledAcquires:  s.CanceledAcquires,
+		})
+	}
+
+	return shows
+}
+
+type QueryStatShow struct {
+	Fingerprint string          `json:"fingerprint"`
+	Calls       int64           `json:"calls"`
+	Errors      int64           `json:"errors"`
+	TotalMs     float64         `json:"totalMs"`
+	MeanMs      float64         `json:"meanMs"`
+	P99Ms       float64         `json:"p99Ms"`
+	MaxMs       float64         `json:"maxMs"`
+	Plan        json.RawMessage `json:"plan,omitempty"`
+	PlanTime    *time.Time      `json:"planTime,omitempty"`
+}
+
+func QueryStatsToShow(stats []database.QueryStat) []QueryStatShow {
+	shows := make([]QueryStatShow, 0, len(stats))
+	for _, s := range stats {
+		show := QueryStatShow{
+			Fingerprint: s.Fingerprint,
+			Calls:       s.Calls,
+			Errors:      s.Errors,
+			TotalMs:     ms(s.Total),
+			MeanMs:      ms(s.Mean),
+			P99Ms:       ms(s.P99),
+			MaxMs:       ms(s.Max),
+			Plan:        s.Plan,
+		}
+		if !s.PlanTime.IsZero() {
+			show.PlanTime = &s.PlanTime
+		}
+		shows = append(shows, show)
+	}
+
+	return shows
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
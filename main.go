@@ -3,60 +3,109 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"goroutines/config"
 	"goroutines/pkg/database"
-	"goroutines/pkg/env"
+	"goroutines/pkg/logging"
+	"goroutines/pkg/metrics"
+	"goroutines/pkg/tracing"
 	routes "goroutines/router"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	// Flags override the environment and the config file, subcommands come after them
+	flags := config.BindFlags(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	// The load generator only talks HTTP, it needs neither .env nor a database
+	if command == "bench" {
+		os.Exit(runBench(args[1:]))
+	}
+
+	// .env is optional, it never overrides variables already set
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		fmt.Fprintf(os.Stderr, "Failed to load .env %v\n", err)
 		os.Exit(1)
 	}
 
-	cfg, err := config.New()
+	// config print shows an invalid configuration too, along with what is wrong with it
+	cfg, err := config.Load(flags.Sources())
+	if command == "config" {
+		os.Exit(runConfig(cfg, err, args[1:]))
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
 		os.Exit(1)
 	}
 
-	// Shared ctx
-	ctx := context.Background()
+	// Structured logs in the configured format and level, secrets scrubbed from every line
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log))
 
-	// Connect to the database
-	db, err := database.New(ctx, cfg.DB)
+	if command == "migrate" {
+		os.Exit(runMigrate(cfg, args[1:]))
+	}
+
+	// Root ctx, canceled on shutdown to stop the background work
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if command == "seed" {
+		os.Exit(runSeed(ctx, cfg, args[1:]))
+	}
+
+	// Spans are exported from here on, the global tracer is a no-op before
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		slog.Error("Unable to set up tracing", slog.Any("error", err))
 		os.Exit(1)
 	}
-	defer db.Close()
 
-	fmt.Printf("Successfully connected to database %v %s", cfg.DB, "\n")
+	// db stays nil with the memory storage
+	var db *database.DB
+	if cfg.App.Storage == config.StorageMemory {
+		slog.Warn("Running with in-memory storage, data is lost on exit and no database is used",
+			slog.String("storage", cfg.App.Storage),
+			slog.String("fixtures", cfg.App.FixturesFile))
+	} else {
+		db, err = connect(ctx, cfg)
+		if err != nil {
+			slog.Error("Unable to start", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
 
 	// Disable debug mode in production
-	if env.IsProduction() {
+	if cfg.App.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Check reachability
-	if _, err = db.Exec(ctx, `SELECT 1`); err != nil {
-		errMsg := fmt.Errorf("pool.Exec() error: %v", err)
-		fmt.Println(errMsg) // or handle the error message in some other way
-	}
-
 	// Prepare router
-	router := gin.New()
+	stats := metrics.New(db)
+	router := newRouter(cfg, stats)
 
 	// Register routes
-	routes.RegisterRouter(ctx, db, router)
+	checks := newHealth(cfg, db)
+	if err := routes.RegisterRouter(ctx, cfg, db, checks, stats, router); err != nil {
+		slog.Error("Unable to register routes", slog.Any("error", err))
+		os.Exit(1)
+	}
 
 	// Prepare server
 	serveAddr := ":" + fmt.Sprint(cfg.App.Port)
@@ -64,10 +113,53 @@ func main() {
 		Addr:    serveAddr,
 		Handler: router,
 	}
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		slog.Error("Unable to listen", slog.String("addr", serveAddr), slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Start http server until SIGINT or SIGTERM
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Serving", slog.String("url", fmt.Sprintf("http://%s:%d", cfg.App.Host, cfg.App.Port)))
+	exitCode := 0
+	if err := serve(signalCtx, server, listener, cfg.App.ShutdownDrainDelay, cfg.App.ShutdownTimeout, checks, cancel); err != nil {
+		slog.Error("Unclean shutdown", slog.Any("error", err))
+		exitCode = 1
+	}
+
+	if db != nil {
+		db.Close()
+	}
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Unable to flush spans", slog.Any("error", err))
+	}
+	flushCancel()
+	slog.Info("Stopped")
+	// Flush what the log handler wrote, stderr may be a file
+	os.Stderr.Sync()
+	os.Exit(exitCode)
+}
+
+// connect migrates the database when MIGRATE_ON_START is set, then opens the pools
+func connect(ctx context.Context, cfg *config.Container) (*database.DB, error) {
+	// Apply pending migrations, replicas starting together wait on the advisory lock
+	if cfg.DB.MigrateOnStart {
+		if err := migrateOnStart(ctx, cfg.DB); err != nil {
+			return nil, fmt.Errorf("Unable to migrate database: %w", err)
+		}
+	}
 
-	// Start http server
-	fmt.Printf("Serving on http://localhost:%s\n", fmt.Sprint(cfg.App.Port))
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		fmt.Printf("HTTP server error: %s\n", err)
+	db, err := database.New(ctx, cfg.DB)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to database: %w", err)
 	}
+
+	// Reachability is reported by /readyz from now on
+	slog.Info("Connected to database", slog.String("db", cfg.DB.String()))
+
+	return db, nil
 }
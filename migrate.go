@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"goroutines/config"
+	"goroutines/pkg/migrate"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [N]        roll back N migrations, 1 by default
+  down -all       roll back every migration
+  goto VERSION    migrate up or down to VERSION
+  version         print the current version
+  force VERSION   set VERSION without running anything, clears the dirty flag`
+
+// runMigrate runs the migrate subcommand and returns the process exit code
+func runMigrate(cfg *config.Container, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	m, err := migrate.New(cfg.DB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to prepare migrations: %v\n", err)
+		return 1
+	}
+	defer m.Close()
+
+	switch cmd, arg := args[0], args[1:]; cmd {
+	case "up":
+		err = m.Up()
+	case "down":
+		n := 1
+		if len(arg) > 0 && arg[0] == "-all" {
+			n = 0
+		} else if len(arg) > 0 {
+			if n, err = strconv.Atoi(arg[0]); err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Invalid step count %q\n", arg[0])
+				return 2
+			}
+		}
+		err = m.Down(n)
+	case "goto":
+		if len(arg) == 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.ParseUint(arg[0], 10, 64)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Invalid version %q\n", arg[0])
+			return 2
+		}
+		err = m.Goto(uint(version))
+	case "force":
+		if len(arg) == 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.Atoi(arg[0])
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Invalid version %q\n", arg[0])
+			return 2
+		}
+		err = m.Force(version)
+	case "version":
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Migration %s failed: %v\n", args[0], err)
+		return 1
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read migration version: %v\n", err)
+		return 1
+	}
+	fmt.Printf("version=%d dirty=%t\n", version, dirty)
+
+	return 0
+}
+
+func migrateOnStart(ctx context.Context, cfg *config.DB) error {
+	m, err := migrate.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return m.UpLocked(ctx)
+}
@@ -1,8 +1,9 @@
 package api
 
 import (
-	"fmt"
+	"goroutines/pkg/logging"
 	"io"
+	"log/slog"
 	"net/http"
 )
 
@@ -55,7 +56,10 @@ func (c *Client) NewRequest(method, url string, body io.Reader) (*http.Request,
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	res, err := c.Client.Do(req)
 	if err != nil {
-		fmt.Print("err", err)
+		logging.FromContext(req.Context()).Error("HTTP request failed",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.String()),
+			slog.Any("error", err))
 		return nil, err
 	}
 
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"goroutines/util"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+const (
+	// Durations kept per fingerprint to compute the p99
+	analyticsWindow = 1024
+	// Distinct fingerprints tracked, later ones are folded into analyticsOverflow
+	analyticsMaxFingerprints = 1000
+	analyticsOverflow        = "<other>"
+
+	explainTimeout = 5 * time.Second
+)
+
+const (
+	SortByTotal = "total"
+	SortByP99   = "p99"
+)
+
+var (
+	fingerprintLiteral     = regexp.MustCompile(`'(?:[^']|'')*'|\$\d+|\b\d+(?:\.\d+)?\b`)
+	fingerprintList        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintValues      = regexp.MustCompile(`(?i)(VALUES\s*\(\.\.\.\))(?:\s*,\s*\(\.\.\.\))+`)
+	fingerprintWhitespace  = regexp.MustCompile(`\s+`)
+	explainableStatement   = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH)\b`)
+	skipAnalyticsKey       = struct{ name string }{"skip query analytics"}
+	queryAnalyticsTraceKey = struct{ name string }{"query analytics trace"}
+)
+
+// QueryStat is the summary of every execution of one SQL fingerprint
+type QueryStat struct {
+	Fingerprint string
+	Calls       int64
+	Errors      int64
+	Total       time.Duration
+	Mean        time.Duration
+	P99         time.Duration
+	Max         time.Duration
+	// Plan is the last EXPLAIN (ANALYZE, FORMAT JSON) captured for a slow execution
+	Plan     json.RawMessage
+	PlanTime time.Time
+}
+
+type queryStats struct {
+	calls  int64
+	errors int64
+	total  time.Duration
+	max    time.Duration
+	window [analyticsWindow]time.Duration
+	plan   json.RawMessage
+	planAt time.Time
+}
+
+type queryTrace struct {
+	start time.Time
+	sql   string
+	args  []any
+}
+
+type batchTrace struct {
+	last time.Time
+}
+
+// QueryAnalytics is a pgx tracer recording the duration of every statement by fingerprint.
+// Statements slower than the threshold are logged with their argument count, never their
+// values, and a sample of them is explained on a separate connection.
+type QueryAnalytics struct {
+	threshold  time.Duration
+	sampleRate float64
+	logger     *PGXStdLogger
+
+	// pool runs the EXPLAIN of sampled slow statements, set once the primary pool exists
+	pool       atomic.Pointer[pgxpool.Pool]
+	explaining atomic.Bool
+
+	mu    sync.Mutex
+	stats map[string]*queryStats
+}
+
+var (
+	_ pgx.QueryTracer = (*QueryAnalytics)(nil)
+	_ pgx.BatchTracer = (*QueryAnalytics)(nil)
+)
+
+func NewQueryAnalytics(threshold time.Duration, sampleRate float64, logger *PGXStdLogger) *QueryAnalytics {
+	return &QueryAnalytics{
+		threshold:  threshold,
+		sampleRate: sampleRate,
+		logger:     logger,
+		stats:      make(map[string]*queryStats),
+	}
+}
+
+func (a *QueryAnalytics) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if ctx.Value(skipAnalyticsKey) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryAnalyticsTraceKey, &queryTrace{
+		start: time.Now(),
+		sql:   data.SQL,
+		args:  data.Args,
+	})
+}
+
+func (a *QueryAnalytics) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryAnalyticsTraceKey).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	a.observe(ctx, trace.sql, trace.args, time.Since(trace.start), data.Err)
+}
+
+func (a *QueryAnalytics) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	if ctx.Value(skipAnalyticsKey) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryAnalyticsTraceKey, &batchTrace{last: time.Now()})
+}
+
+// TraceBatchQuery is called as each result is read, so a statement is charged the time
+// elapsed since the previous one of the same batch.
+func (a *QueryAnalytics) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	trace, ok := ctx.Value(queryAnalyticsTraceKey).(*batchTrace)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	a.observe(ctx, data.SQL, data.Args, now.Sub(trace.last), data.Err)
+	trace.last = now
+}
+
+func (a *QueryAnalytics) TraceBatchEnd(context.Context, *pgx.Conn, pgx.TraceBatchEndData) {}
+
+func (a *QueryAnalytics) observe(ctx context.Context, sql string, args []any, elapsed time.Duration, err error) {
+	fingerprint := Fingerprint(sql)
+
+	a.mu.Lock()
+	s, ok := a.stats[fingerprint]
+	if !ok {
+		if len(a.stats) >= analyticsMaxFingerprints {
+			fingerprint = analyticsOverflow
+			s = a.stats[fingerprint]
+		}
+		if s == nil {
+			s = &queryStats{}
+			a.stats[fingerprint] = s
+		}
+	}
+	s.window[s.calls%analyticsWindow] = elapsed
+	s.calls++
+	s.total += elapsed
+	if elapsed > s.max {
+		s.max = elapsed
+	}
+	if err != nil {
+		s.errors++
+	}
+	a.mu.Unlock()
+
+	if a.threshold <= 0 || elapsed < a.threshold {
+		return
+	}
+
+	a.logger.Log(ctx, tracelog.LogLevelWarn, "Slow query", map[string]any{
+		"fingerprint": fingerprint,
+		"duration":    elapsed,
+		"threshold":   a.threshold,
+		"args":        len(args),
+	})
+
+	if err == nil && rand.Float64() < a.sampleRate && explainableStatement.MatchString(sql) {
+		a.explain(fingerprint, sql, args)
+	}
+}
+
+// explain captures the plan of a slow statement in the background, one at a time.
+// The statement really runs under ANALYZE, so it is wrapped in a rolled back transaction.
+func (a *QueryAnalytics) explain(fingerprint, sql string, args []any) {
+	pool := a.pool.Load()
+	if pool == nil || !a.explaining.CompareAndSwap(false, true) {
+		return
+	}
+
+	util.GoSafe("query explain", func() {
+		defer a.explaining.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), skipAnalyticsKey, true), explainTimeout)
+		defer cancel()
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		var plan []byte
+		if err := tx.QueryRow(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+sql, args...).Scan(&plan); err != nil {
+			a.logger.Log(ctx, tracelog.LogLevelDebug, "Cannot explain slow query", map[string]any{
+				"fingerprint": fingerprint,
+				"err":         err,
+			})
+			return
+		}
+
+		a.mu.Lock()
+		if s, ok := a.stats[fingerprint]; ok {
+			s.plan = plan
+			s.planAt = time.Now()
+		}
+		a.mu.Unlock()
+	})
+}
+
+// Top returns the n fingerprints with the highest total (SortByTotal) or p99 (SortByP99) time
+func (a *QueryAnalytics) Top(n int, by string) []QueryStat {
+	a.mu.Lock()
+	result := make([]QueryStat, 0, len(a.stats))
+	for fingerprint, s := range a.stats {
+		size := s.calls
+		if size > analyticsWindow {
+			size = analyticsWindow
+		}
+		window := make([]time.Duration, size)
+		copy(window, s.window[:size])
+
+		result = append(result, QueryStat{
+			Fingerprint: fingerprint,
+			Calls:       s.calls,
+			Errors:      s.errors,
+			Total:       s.total,
+			Mean:        s.total / time.Duration(s.calls),
+			P99:         percentile(window, 0.99),
+			Max:         s.max,
+			Plan:        s.plan,
+			PlanTime:    s.planAt,
+		})
+	}
+	a.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if by == SortByP99 {
+			return result[i].P99 > result[j].P99
+		}
+		return result[i].Total > result[j].Total
+	})
+
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+
+	return result
+}
+
+// Fingerprint normalizes sql so executions differing only in literals, placeholders,
+// list lengths or whitespace share one entry.
+func Fingerprint(sql string) string {
+	fp := fingerprintLiteral.ReplaceAllString(sql, "?")
+	fp = fingerprintList.ReplaceAllString(fp, "(...)")
+	fp = fingerprintWhitespace.ReplaceAllString(fp, " ")
+	fp = fingerprintValues.ReplaceAllString(fp, "$1")
+
+	return strings.TrimSpace(fp)
+}
+
+func percentile(window []time.Duration, p float64) time.Duration {
+	if len(window) == 0 {
+		return 0
+	}
+
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	idx := int(float64(len(window)-1) * p)
+
+	return window[idx]
+}
+
+// TopQueries returns the query analytics of every pool, see QueryAnalytics.Top
+func (db *DB) TopQueries(n int, by string) []QueryStat {
+	return db.analytics.Top(n, by)
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t,
+		"SELECT * FROM categories WHERE name = ? LIMIT ?",
+		Fingerprint("SELECT *\n\tFROM categories WHERE name = $1 LIMIT 1"))
+	assert.Equal(t,
+		"SELECT * FROM products WHERE id IN (...) AND name = ?",
+		Fingerprint("SELECT * FROM products WHERE id IN ($1, $2, $3) AND name = 'it''s'"))
+	assert.Equal(t,
+		"INSERT INTO products (name,sku) VALUES (...)",
+		Fingerprint("INSERT INTO products (name,sku) VALUES ($1,$2),($3,$4)"))
+}
+
+func TestQueryAnalyticsTop(t *testing.T) {
+	a := NewQueryAnalytics(0, 0, &PGXStdLogger{Logger: slog.Default()})
+
+	run := func(sql string, d time.Duration) {
+		ctx := a.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql})
+		ctx.Value(queryAnalyticsTraceKey).(*queryTrace).start = time.Now().Add(-d)
+		a.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	}
+
+	for i := 0; i < 10; i++ {
+		run("SELECT 1", time.Millisecond)
+	}
+	run("SELECT 2 FROM products", 5*time.Millisecond)
+
+	byTotal := a.Top(10, SortByTotal)
+	assert.Len(t, byTotal, 2)
+	assert.Equal(t, "SELECT ?", byTotal[0].Fingerprint)
+	assert.Equal(t, int64(10), byTotal[0].Calls)
+
+	byP99 := a.Top(1, SortByP99)
+	assert.Len(t, byP99, 1)
+	assert.Equal(t, "SELECT ? FROM products", byP99[0].Fingerprint)
+}
@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"goroutines/config"
-	"goroutines/pkg/env"
+	dbErrs "goroutines/pkg/database/errs"
 	"log/slog"
 
 	"github.com/Masterminds/squirrel"
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
 )
@@ -18,87 +18,142 @@ import (
 type DB struct {
 	*pgxpool.Pool
 	QueryBuilder *squirrel.StatementBuilderType
-	url          string
+	// url is the primary connection string with the password masked
+	url       string
+	logger    *PGXStdLogger
+	replicas  *replicaSet
+	analytics *QueryAnalytics
+	readOnly  bool
+	stop      context.CancelFunc
 }
 
+// New connects to the primary and the replicas. The pool monitors and replica health checks
+// run until ctx is canceled or Close is called.
 func New(ctx context.Context, config *config.DB) (*DB, error) {
-	pgUrl := `postgres://%s:%s@%s:%d/%s?%s`
-	pgUrl = fmt.Sprintf(pgUrl,
-		config.Username,
-		config.Pass,
-		config.Host,
-		config.Port,
-		config.Name,
-		config.Params,
-	)
+	pgUrl := connString(config, config.Host, config.Port)
 
-	conf, err := pgxpool.ParseConfig(pgUrl)
-	if err != nil {
-		return nil, err
+	logger := &PGXStdLogger{
+		Logger:    slog.Default(),
+		ElideArgs: !config.LogArgs,
 	}
 
-	// Only show on development mode
-	if !env.IsProduction() {
-		conf.ConnConfig.Tracer = &tracelog.TraceLog{
-			Logger: &PGXStdLogger{
-				slog.Default(),
-			},
+	analytics := NewQueryAnalytics(config.SlowQueryThreshold, config.ExplainSampleRate, logger)
+	tracer := multiTracer{analytics, newOtelTracer()}
+
+	// Every statement is logged outside production
+	if config.LogQueries {
+		tracer = append(tracer, &tracelog.TraceLog{
+			Logger:   logger,
 			LogLevel: tracelog.LogLevelInfo,
+		})
+	}
+
+	// Refuse to serve a schema the code doesn't expect, or only serve reads
+	readOnly := false
+	if err := checkSchema(ctx, pgUrl); err != nil {
+		var mismatch *SchemaMismatchError
+		if config.SchemaMismatch != SchemaMismatchReadOnly || !errors.As(err, &mismatch) {
+			return nil, err
 		}
+		logger.Log(ctx, tracelog.LogLevelWarn, "Schema version mismatch, serving read only", map[string]any{
+			"expected": mismatch.Expected,
+			"actual":   mismatch.Actual,
+			"dirty":    mismatch.Dirty,
+		})
+		readOnly = true
 	}
 
-	// pgxpool default max number of connections is the number of CPUs on your machine returned by runtime.NumCPU().
-	// This number is very conservative, and you might be able to improve performance for highly concurrent applications
-	// by increasing it.
-	// conf.MaxConns = runtime.NumCPU() * 5
-	pool, err := pgxpool.NewWithConfig(ctx, conf)
+	pool, err := newPool(ctx, pgUrl, &config.Pool, tracer, readOnly)
 	if err != nil {
-		return nil, fmt.Errorf("pgx connection error: %w", err)
+		return nil, err
 	}
+	analytics.pool.Store(pool)
 
 	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	replicas, err := newReplicaSet(ctx, config, logger, tracer)
+	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-	return &DB{
-		pool,
-		&psql,
-		pgUrl,
-	}, nil
+	db := &DB{
+		Pool:         pool,
+		QueryBuilder: &psql,
+		url:          config.String(),
+		logger:       logger,
+		replicas:     replicas,
+		analytics:    analytics,
+		readOnly:     readOnly,
+	}
+
+	// Background work stops with ctx or on Close, whichever comes first
+	monitorCtx, stop := context.WithCancel(ctx)
+	db.stop = stop
+	db.monitorAcquireWaits(monitorCtx, config.Pool.AcquireWarnThreshold)
+
+	return db, nil
 }
 
-func (db *DB) BeginTransaction(ctx context.Context, f func(tx pgx.Tx, ctx context.Context) error) error {
-	tx, err := db.Pool.Begin(ctx)
+func connString(config *config.DB, host string, port int) string {
+	pgUrl := `postgres://%s:%s@%s:%d/%s?%s`
+	return fmt.Sprintf(pgUrl,
+		config.Username,
+		config.Pass,
+		host,
+		port,
+		config.Name,
+		config.Params,
+	)
+}
+
+func newPool(ctx context.Context, pgUrl string, tuning *config.Pool, tracer pgx.QueryTracer, readOnly bool) (*pgxpool.Pool, error) {
+	conf, err := pgxpool.ParseConfig(pgUrl)
 	if err != nil {
-		return fmt.Errorf("Begin %w", err)
+		return nil, err
 	}
-
-	if err := f(tx, ctx); err != nil {
-		_ = tx.Rollback(ctx)
-
-		return fmt.Errorf("f %w", err)
+	conf.ConnConfig.Tracer = tracer
+	if readOnly {
+		conf.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("Commit %w", err)
+	// pgxpool default max number of connections is the number of CPUs on your machine returned by runtime.NumCPU().
+	// This number is very conservative, and you might be able to improve performance for highly concurrent applications
+	// by increasing it, e.g. DB_MAX_CONNS to runtime.NumCPU() * 5.
+	applyPoolTuning(conf, tuning)
+
+	pool, err := pgxpool.NewWithConfig(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("pgx connection error: %w", err)
 	}
 
-	return nil
+	return pool, nil
 }
 
-// ErrorCode returns the error code of the given error
+// ErrorCode classifies the given Postgres error into a typed dbErrs.Error, translated to its
+// domain sentinel when the violated constraint is registered. It returns nil for other errors.
 func (db *DB) ErrorCode(err error) error {
 	var pgErr *pgconn.PgError
 	if !errors.As(err, &pgErr) {
 		return nil
 	}
 
-	return errors.New(pgErr.Code)
+	return dbErrs.Translate(err)
+}
+
+// ReadOnly reports whether the primary only accepts reads because of a schema mismatch
+func (db *DB) ReadOnly() bool {
+	return db.readOnly
 }
 
-// Close closes the database connection
+// Close closes the database connections, replicas included
 func (db *DB) Close() {
+	db.stop()
+	db.replicas.Close()
 	db.Pool.Close()
 }
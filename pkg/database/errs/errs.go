@@ -0,0 +1,142 @@
+// Package errs classifies Postgres errors into typed errors usable with errors.Is,
+// and translates constraint violations into domain sentinels.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Kinds of database failures, matched with errors.Is
+var (
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrExclusionViolation   = errors.New("exclusion violation")
+	ErrStringTooLong        = errors.New("string data right truncation")
+	ErrInvalidText          = errors.New("invalid text representation")
+	ErrNumericOutOfRange    = errors.New("numeric value out of range")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlock             = errors.New("deadlock detected")
+	ErrLockNotAvailable     = errors.New("lock not available")
+	ErrQueryCanceled        = errors.New("query canceled")
+	ErrReadOnly             = errors.New("read only transaction")
+	ErrUndefinedTable       = errors.New("undefined table")
+	ErrUndefinedColumn      = errors.New("undefined column")
+	ErrDatabase             = errors.New("database error")
+)
+
+// SQLSTATE to kind, anything else is ErrDatabase
+var kinds = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23514": ErrCheckViolation,
+	"23502": ErrNotNullViolation,
+	"23P01": ErrExclusionViolation,
+	"22001": ErrStringTooLong,
+	"22P02": ErrInvalidText,
+	"22003": ErrNumericOutOfRange,
+	"40001": ErrSerializationFailure,
+	"40P01": ErrDeadlock,
+	"55P03": ErrLockNotAvailable,
+	"57014": ErrQueryCanceled,
+	"25006": ErrReadOnly,
+	"42P01": ErrUndefinedTable,
+	"42703": ErrUndefinedColumn,
+}
+
+// Error is a classified *pgconn.PgError. Both errors.Is(err, Kind) and
+// errors.As(err, **pgconn.PgError) work on it.
+type Error struct {
+	Kind       error
+	Code       string
+	Table      string
+	Column     string
+	Constraint string
+	Detail     string
+	Err        *pgconn.PgError
+}
+
+func (e *Error) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%s (%s) on %q: %s", e.Kind, e.Code, e.Constraint, e.Err.Message)
+	}
+
+	return fmt.Sprintf("%s (%s): %s", e.Kind, e.Code, e.Err.Message)
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Classify returns err as an *Error when it wraps a *pgconn.PgError, err unchanged otherwise
+func Classify(err error) error {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	kind, ok := kinds[pgErr.Code]
+	if !ok {
+		kind = ErrDatabase
+	}
+
+	return &Error{
+		Kind:       kind,
+		Code:       pgErr.Code,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Constraint: pgErr.ConstraintName,
+		Detail:     pgErr.Detail,
+		Err:        pgErr,
+	}
+}
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = map[string]error{}
+)
+
+// RegisterConstraint maps a constraint (or unique index) name to a domain sentinel
+func RegisterConstraint(name string, sentinel error) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+
+	constraints[name] = sentinel
+}
+
+// RegisterConstraints registers every name to sentinel pair of m
+func RegisterConstraints(m map[string]error) {
+	for name, sentinel := range m {
+		RegisterConstraint(name, sentinel)
+	}
+}
+
+// Translate classifies err and, when the violated constraint is registered, wraps it
+// with the domain sentinel, so errors.Is matches the sentinel and the kind alike.
+func Translate(err error) error {
+	err = Classify(err)
+
+	var classified *Error
+	if !errors.As(err, &classified) || classified.Constraint == "" {
+		return err
+	}
+
+	constraintsMu.RLock()
+	sentinel, ok := constraints[classified.Constraint]
+	constraintsMu.RUnlock()
+	if !ok {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
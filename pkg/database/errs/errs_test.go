@@ -0,0 +1,44 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "products_pkey", Message: "duplicate key"}
+	err := Classify(fmt.Errorf("f %w", pgErr))
+
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+
+	var classified *Error
+	if assert.ErrorAs(t, err, &classified) {
+		assert.Equal(t, "products_pkey", classified.Constraint)
+	}
+
+	var original *pgconn.PgError
+	assert.ErrorAs(t, err, &original)
+	assert.Same(t, pgErr, original)
+
+	assert.ErrorIs(t, Classify(&pgconn.PgError{Code: "XX000"}), ErrDatabase)
+
+	plain := errors.New("plain")
+	assert.Same(t, plain, Classify(plain))
+}
+
+func TestTranslate(t *testing.T) {
+	errDuplicate := errors.New("duplicate")
+	RegisterConstraint("test_unique", errDuplicate)
+
+	err := Translate(&pgconn.PgError{Code: "23505", ConstraintName: "test_unique"})
+	assert.ErrorIs(t, err, errDuplicate)
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+
+	err = Translate(&pgconn.PgError{Code: "23505", ConstraintName: "other"})
+	assert.NotErrorIs(t, err, errDuplicate)
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+}
@@ -2,24 +2,36 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"goroutines/pkg/logging"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/tracelog"
 )
 
 // PGXStdLogger prints pgx logs to the standard logger.
-// os.Stderr by default.
+// os.Stderr by default. Queries of a request log with the request logger, its ID attached.
 type PGXStdLogger struct {
 	Logger *slog.Logger
+	// ElideArgs replaces the bound query arguments with their count
+	ElideArgs bool
 }
 
 func (l *PGXStdLogger) Log(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
 	attrs := make([]slog.Attr, 0, len(data)+1)
 	attrs = append(attrs, slog.String("pgx_level", level.String()))
 	for k, v := range data {
+		if args, ok := v.([]any); ok && k == "args" && l.ElideArgs {
+			v = fmt.Sprintf("[%d elided]", len(args))
+		}
 		attrs = append(attrs, slog.Any(k, v))
 	}
-	l.Logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+
+	logger := l.Logger
+	if requestLogger, ok := logging.Lookup(ctx); ok {
+		logger = requestLogger
+	}
+	logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
 }
 
 // slogLevel translates pgx log level to slog log level.
@@ -0,0 +1,91 @@
+// Package memory backs in-memory repositories: a fake transaction manager with rollback
+// support, and the errors Postgres would return for the same violations.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"goroutines/pkg/database"
+	dbErrs "goroutines/pkg/database/errs"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type txKey struct{}
+
+// tx records how to undo the writes of one unit of work
+type tx struct {
+	undo []func()
+}
+
+// TxManager is an in-memory database.TxManager. Transactions run one at a time, and the
+// writes of a failed one are undone in reverse order through the callbacks registered with
+// OnRollback. Writes outside a transaction are not isolated from it.
+//
+// f is handed a nil pgx.Tx, only the ctx carries the transaction.
+type TxManager struct {
+	mu sync.Mutex
+}
+
+var _ database.TxManager = (*TxManager)(nil)
+
+func NewTxManager() *TxManager {
+	return &TxManager{}
+}
+
+// BeginTransaction runs f in a transaction, nested calls behave like savepoints.
+// The options are ignored, transactions are serialized anyway.
+func (m *TxManager) BeginTransaction(ctx context.Context, f func(tx pgx.Tx, ctx context.Context) error, _ ...database.TxOption) error {
+	parent, nested := ctx.Value(txKey{}).(*tx)
+	if !nested {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("Begin %w", err)
+	}
+
+	current := &tx{}
+	if err := f(nil, context.WithValue(ctx, txKey{}, current)); err != nil {
+		current.rollback()
+		return fmt.Errorf("f %w", err)
+	}
+
+	// A savepoint is released into its parent, which may still roll it back
+	if nested {
+		parent.undo = append(parent.undo, current.undo...)
+	}
+
+	return nil
+}
+
+func (t *tx) rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.undo = nil
+}
+
+// OnRollback registers undo to run if the transaction carried by ctx rolls back.
+// Outside a transaction the write is final and undo is dropped.
+func OnRollback(ctx context.Context, undo func()) {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.undo = append(t.undo, undo)
+	}
+}
+
+// UniqueViolation returns the error Postgres raises when constraint of table is violated,
+// translated like the Postgres repositories do so registered domain sentinels match.
+func UniqueViolation(table, constraint, detail string) error {
+	return dbErrs.Translate(&pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Detail:         detail,
+		TableName:      table,
+		ConstraintName: constraint,
+	})
+}
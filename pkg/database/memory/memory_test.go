@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	dbErrs "goroutines/pkg/database/errs"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// set is a minimal store undoing its writes on rollback
+type set struct {
+	mu    sync.Mutex
+	items map[string]bool
+}
+
+func (s *set) add(ctx context.Context, item string) {
+	s.mu.Lock()
+	s.items[item] = true
+	s.mu.Unlock()
+
+	OnRollback(ctx, func() {
+		s.mu.Lock()
+		delete(s.items, item)
+		s.mu.Unlock()
+	})
+}
+
+func TestTxManager(t *testing.T) {
+	errFailed := errors.New("failed")
+	ctx := context.Background()
+	m := NewTxManager()
+
+	s := &set{items: map[string]bool{}}
+	s.add(ctx, "outside")
+
+	err := m.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+		s.add(ctx, "committed")
+
+		// A failed savepoint only undoes its own writes
+		err := m.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+			s.add(ctx, "savepoint")
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"outside": true, "committed": true}, s.items)
+
+	err = m.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+		s.add(ctx, "rolled back")
+		// A released savepoint is rolled back with its parent
+		_ = m.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+			s.add(ctx, "released")
+			return nil
+		})
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, map[string]bool{"outside": true, "committed": true}, s.items)
+}
+
+func TestUniqueViolation(t *testing.T) {
+	sentinel := errors.New("already exists")
+	dbErrs.RegisterConstraint("memory_test_pkey", sentinel)
+
+	err := UniqueViolation("memory_test", "memory_test_pkey", "Key (id)=(1) already exists.")
+
+	assert.ErrorIs(t, err, dbErrs.ErrUniqueViolation)
+	assert.ErrorIs(t, err, sentinel)
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"goroutines/pkg/tracing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelTracer opens a client span per query, batch, copy, connect and pool acquire.
+// Work without a span in its context, like the pool health checks and monitors, is not traced,
+// so every span belongs to a request or a job trace.
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+var (
+	_ pgx.QueryTracer       = (*otelTracer)(nil)
+	_ pgx.BatchTracer       = (*otelTracer)(nil)
+	_ pgx.ConnectTracer     = (*otelTracer)(nil)
+	_ pgx.CopyFromTracer    = (*otelTracer)(nil)
+	_ pgxpool.AcquireTracer = (*otelTracer)(nil)
+)
+
+// otelSpanKey marks the span opened by the tracer, so the end hook never ends the caller's span
+type otelSpanKey struct{}
+
+func newOtelTracer() *otelTracer {
+	return &otelTracer{tracing.Tracer()}
+}
+
+func (t *otelTracer) start(ctx context.Context, name string, attrs ...attribute.KeyValue) context.Context {
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return ctx
+	}
+
+	attrs = append(attrs, semconv.DBSystemPostgreSQL)
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return context.WithValue(ctx, otelSpanKey{}, span)
+}
+
+func (t *otelTracer) end(ctx context.Context, err error, attrs ...attribute.KeyValue) {
+	span, ok := ctx.Value(otelSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(attrs...)
+	tracing.End(span, err)
+}
+
+func (t *otelTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	// Arguments are never recorded, they may carry personal data
+	return t.start(ctx, "pgx.query", semconv.DBQueryText(data.SQL))
+}
+
+func (t *otelTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.Err, attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *otelTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return t.start(ctx, "pgx.batch", attribute.Int("db.batch.size", data.Batch.Len()))
+}
+
+func (t *otelTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if span, ok := ctx.Value(otelSpanKey{}).(trace.Span); ok && data.Err != nil {
+		span.RecordError(data.Err, trace.WithAttributes(semconv.DBQueryText(data.SQL)))
+	}
+}
+
+func (t *otelTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, data.Err)
+}
+
+func (t *otelTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return t.start(ctx, "pgx.copy_from", semconv.DBCollectionName(data.TableName.Sanitize()))
+}
+
+func (t *otelTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	t.end(ctx, data.Err, attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *otelTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	return t.start(ctx, "pgx.connect", semconv.ServerAddress(data.ConnConfig.Host))
+}
+
+func (t *otelTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	t.end(ctx, data.Err)
+}
+
+func (t *otelTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return t.start(ctx, "pgxpool.acquire")
+}
+
+func (t *otelTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	t.end(ctx, data.Err)
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOtelTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := &otelTracer{sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")}
+
+	// Background work has no span, it is not traced
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	assert.Empty(t, recorder.Ended())
+
+	parentCtx, parent := tracer.tracer.Start(context.Background(), "request")
+
+	ctx = tracer.TraceAcquireStart(parentCtx, nil, pgxpool.TraceAcquireStartData{})
+	tracer.TraceAcquireEnd(ctx, nil, pgxpool.TraceAcquireEndData{})
+
+	ctx = tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{SQL: "INSERT INTO product", Args: []any{"secret"}})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("INSERT 0 1"), Err: errors.New("boom")})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3, "the caller's span is ended once, by the caller")
+	acquire, query := spans[0], spans[1]
+
+	assert.Equal(t, "pgxpool.acquire", acquire.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), acquire.Parent().SpanID())
+
+	assert.Equal(t, "pgx.query", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, codes.Error, query.Status().Code)
+	for _, attr := range query.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "secret", "arguments are not recorded")
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"goroutines/config"
+	"goroutines/util"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+// acquireSamplePeriod is how often the acquire waits are compared against the warning threshold
+const acquireSamplePeriod = 10 * time.Second
+
+// PoolStat is a snapshot of pgxpool.Stat for one pool
+type PoolStat struct {
+	Name             string
+	Acquired         int32
+	Idle             int32
+	Constructing     int32
+	Total            int32
+	Max              int32
+	AcquireCount     int64
+	WaitCount        int64
+	AcquireDuration  time.Duration
+	CanceledAcquires int64
+}
+
+func applyPoolTuning(conf *pgxpool.Config, tuning *config.Pool) {
+	if tuning == nil {
+		return
+	}
+
+	if tuning.MaxConns > 0 {
+		conf.MaxConns = tuning.MaxConns
+	}
+	if tuning.MinConns > 0 {
+		conf.MinConns = tuning.MinConns
+	}
+	if tuning.MaxConnLifetime > 0 {
+		conf.MaxConnLifetime = tuning.MaxConnLifetime
+	}
+	if tuning.MaxConnLifetimeJitter > 0 {
+		conf.MaxConnLifetimeJitter = tuning.MaxConnLifetimeJitter
+	}
+	if tuning.MaxConnIdleTime > 0 {
+		conf.MaxConnIdleTime = tuning.MaxConnIdleTime
+	}
+	if tuning.HealthCheckPeriod > 0 {
+		conf.HealthCheckPeriod = tuning.HealthCheckPeriod
+	}
+}
+
+func poolStat(name string, pool *pgxpool.Pool) PoolStat {
+	stat := pool.Stat()
+
+	return PoolStat{
+		Name:             name,
+		Acquired:         stat.AcquiredConns(),
+		Idle:             stat.IdleConns(),
+		Constructing:     stat.ConstructingConns(),
+		Total:            stat.TotalConns(),
+		Max:              stat.MaxConns(),
+		AcquireCount:     stat.AcquireCount(),
+		WaitCount:        stat.EmptyAcquireCount(),
+		AcquireDuration:  stat.AcquireDuration(),
+		CanceledAcquires: stat.CanceledAcquireCount(),
+	}
+}
+
+// PoolStats returns the statistics of the primary pool followed by the replica pools
+func (db *DB) PoolStats() []PoolStat {
+	stats := []PoolStat{poolStat("primary", db.Pool)}
+	if db.replicas != nil {
+		for _, r := range db.replicas.replicas {
+			stats = append(stats, poolStat(r.name, r.pool))
+		}
+	}
+
+	return stats
+}
+
+// monitorAcquireWaits samples the primary pool and warns when its acquires took, on average,
+// longer than threshold. pgxpool only keeps the total duration of every acquire, so the
+// average is taken over all of them, including the ones served by an idle connection.
+func (db *DB) monitorAcquireWaits(ctx context.Context, threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+
+	util.GoSafe("pool acquire monitor", func() {
+		ticker := time.NewTicker(acquireSamplePeriod)
+		defer ticker.Stop()
+
+		prev := poolStat("primary", db.Pool)
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			cur := poolStat("primary", db.Pool)
+			acquires := cur.AcquireCount - prev.AcquireCount
+			if acquires > 0 {
+				avg := (cur.AcquireDuration - prev.AcquireDuration) / time.Duration(acquires)
+				if avg > threshold {
+					db.logger.Log(ctx, tracelog.LogLevelWarn, "Slow connection acquire", map[string]any{
+						"acquires":    acquires,
+						"waits":       cur.WaitCount - prev.WaitCount,
+						"avg_acquire": avg,
+						"threshold":   threshold,
+						"acquired":    cur.Acquired,
+						"max_conns":   cur.Max,
+						"canceled":    cur.CanceledAcquires - prev.CanceledAcquires,
+						"sample_time": acquireSamplePeriod,
+					})
+				}
+			}
+			prev = cur
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"goroutines/config"
+	"goroutines/util"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+const (
+	ReplicaPolicyRoundRobin = "round-robin"
+	ReplicaPolicyLeastConn  = "least-conn"
+)
+
+// Replay lag in seconds, 0 when every received WAL record is replayed or on a primary
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8`
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// replicaSet routes read only queries to the healthy replicas
+type replicaSet struct {
+	replicas []*replica
+	policy   string
+	maxLag   time.Duration
+	next     atomic.Uint64
+	logger   *PGXStdLogger
+	stop     context.CancelFunc
+}
+
+func newReplicaSet(ctx context.Context, cfg *config.DB, logger *PGXStdLogger, tracer pgx.QueryTracer) (*replicaSet, error) {
+	if len(cfg.Replicas) == 0 {
+		return nil, nil
+	}
+
+	switch cfg.ReplicaPolicy {
+	case ReplicaPolicyRoundRobin, ReplicaPolicyLeastConn:
+	default:
+		return nil, fmt.Errorf("unknown replica policy %q", cfg.ReplicaPolicy)
+	}
+
+	rs := &replicaSet{
+		policy: cfg.ReplicaPolicy,
+		maxLag: cfg.ReplicaMaxLag,
+		logger: logger,
+	}
+	for _, r := range cfg.Replicas {
+		// Pools connect lazily, an unreachable replica only stays out of rotation
+		pool, err := newPool(ctx, connString(cfg, r.Host, r.Port), &cfg.Pool, tracer, false)
+		if err != nil {
+			rs.Close()
+			return nil, err
+		}
+
+		rs.replicas = append(rs.replicas, &replica{
+			name: fmt.Sprintf("%s:%d", r.Host, r.Port),
+			pool: pool,
+		})
+	}
+
+	// The first round decides the initial rotation before any query is routed
+	rs.check(ctx)
+
+	period := cfg.ReplicaCheckPeriod
+	if period <= 0 {
+		period = 5 * time.Second
+	}
+
+	checkCtx, stop := context.WithCancel(ctx)
+	rs.stop = stop
+	util.GoSafe("replica health check", func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				rs.check(checkCtx)
+			case <-checkCtx.Done():
+				return
+			}
+		}
+	})
+
+	return rs, nil
+}
+
+// check pings every replica and measures its lag, updating the rotation
+func (rs *replicaSet) check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		healthy, reason := rs.probe(ctx, r)
+
+		if was := r.healthy.Swap(healthy); was != healthy {
+			level, msg := tracelog.LogLevelInfo, "Replica joined rotation"
+			if !healthy {
+				level, msg = tracelog.LogLevelWarn, "Replica dropped from rotation"
+			}
+			rs.logger.Log(ctx, level, msg, map[string]any{
+				"replica": r.name,
+				"reason":  reason,
+			})
+		}
+	}
+}
+
+func (rs *replicaSet) probe(ctx context.Context, r *replica) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if err := r.pool.Ping(ctx); err != nil {
+		return false, err.Error()
+	}
+
+	var lagSeconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&lagSeconds); err != nil {
+		return false, err.Error()
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	if rs.maxLag > 0 && lag > rs.maxLag {
+		return false, fmt.Sprintf("replication lag %s exceeds %s", lag, rs.maxLag)
+	}
+
+	return true, ""
+}
+
+// pick returns a healthy replica pool, or nil when none is available
+func (rs *replicaSet) pick() *pgxpool.Pool {
+	if rs == nil {
+		return nil
+	}
+
+	switch rs.policy {
+	case ReplicaPolicyLeastConn:
+		var best *pgxpool.Pool
+		var bestConns int32
+		for _, r := range rs.replicas {
+			if !r.healthy.Load() {
+				continue
+			}
+			if conns := r.pool.Stat().AcquiredConns(); best == nil || conns < bestConns {
+				best, bestConns = r.pool, conns
+			}
+		}
+		return best
+	default:
+		n := uint64(len(rs.replicas))
+		start := rs.next.Add(1)
+		for i := uint64(0); i < n; i++ {
+			if r := rs.replicas[(start+i)%n]; r.healthy.Load() {
+				return r.pool
+			}
+		}
+		return nil
+	}
+}
+
+func (rs *replicaSet) Close() {
+	if rs == nil {
+		return
+	}
+
+	if rs.stop != nil {
+		rs.stop()
+	}
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
+
+// Reader returns the querier for read only queries: the transaction carried by ctx so the
+// read sees its writes, else a healthy replica, else the primary.
+func (db *DB) Reader(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	if pool := db.replicas.pick(); pool != nil {
+		return pool
+	}
+
+	return db.Pool
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"goroutines/config"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Needs a primary and at least one more Postgres instance, e.g.:
+//
+//	DB_HOST=localhost DB_PORT=5432 DB_REPLICAS=localhost:5433 DB_USERNAME=postgres DB_PASSWORD=postgres \
+//	DB_NAME=postgres DB_PARAMS=sslmode=disable go test -run TestReplicaRouting ./pkg/database
+func TestReplicaRouting(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" || os.Getenv("DB_REPLICAS") == "" {
+		t.Skip("DB_HOST and DB_REPLICAS not set, skipping replica routing test")
+	}
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	db, err := New(ctx, cfg.DB)
+	require.NoError(t, err)
+	defer db.Close()
+
+	serverPort := func(q Querier) int {
+		var port int
+		require.NoError(t, q.QueryRow(ctx, `SELECT current_setting('port')::int`).Scan(&port))
+		return port
+	}
+
+	replicaPorts := map[int]bool{}
+	for _, r := range cfg.DB.Replicas {
+		replicaPorts[r.Port] = true
+	}
+
+	// Reads go to the replicas, writes to the primary
+	for i := 0; i < 2*len(cfg.DB.Replicas); i++ {
+		assert.True(t, replicaPorts[serverPort(db.Reader(ctx))])
+	}
+	assert.Equal(t, cfg.DB.Port, serverPort(db.Querier(ctx)))
+
+	// Reads inside a transaction stay on the primary
+	require.NoError(t, db.BeginTransaction(ctx, func(_ pgx.Tx, ctx context.Context) error {
+		assert.Equal(t, cfg.DB.Port, serverPort(db.Reader(ctx)))
+		return nil
+	}))
+
+	// A replica out of rotation is skipped, none left falls back to the primary
+	for _, r := range db.replicas.replicas {
+		r.healthy.Store(false)
+	}
+	assert.Equal(t, cfg.DB.Port, serverPort(db.Reader(ctx)))
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goroutines/db/migrations"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	SchemaMismatchFail     = "fail"
+	SchemaMismatchReadOnly = "read-only"
+)
+
+var ErrSchemaMismatch = errors.New("schema version mismatch")
+
+// SchemaMismatchError reports a database whose schema_migrations differs from the embedded migrations
+type SchemaMismatchError struct {
+	Expected uint
+	Actual   uint
+	Dirty    bool
+}
+
+func (e *SchemaMismatchError) Error() string {
+	hint := "run `migrate up` or deploy the matching binary"
+	switch {
+	case e.Dirty:
+		hint = "the last migration failed half way, fix it then run `migrate force`"
+	case e.Actual < e.Expected:
+		hint = "run `migrate up` or set MIGRATE_ON_START=true"
+	case e.Actual > e.Expected:
+		hint = "the database is newer than this binary, deploy the matching release"
+	}
+
+	return fmt.Sprintf("%s: database is at version %d (dirty=%t), binary expects %d: %s",
+		ErrSchemaMismatch, e.Actual, e.Dirty, e.Expected, hint)
+}
+
+func (e *SchemaMismatchError) Unwrap() error {
+	return ErrSchemaMismatch
+}
+
+// SchemaVersion reads the version recorded by golang-migrate, 0 when no migration ran yet
+func SchemaVersion(ctx context.Context, q Querier) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := q.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" { // undefined_table
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint(version), dirty, nil
+}
+
+// checkSchema compares the schema version of the database at pgUrl with the embedded migrations
+func checkSchema(ctx context.Context, pgUrl string) error {
+	conn, err := pgx.Connect(ctx, pgUrl)
+	if err != nil {
+		return fmt.Errorf("pgx connection error: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	return compareSchema(ctx, conn)
+}
+
+// CheckSchema returns a *SchemaMismatchError when the primary schema is no longer the one
+// the binary expects, e.g. after a migration ran behind its back
+func (db *DB) CheckSchema(ctx context.Context) error {
+	return compareSchema(ctx, db.Pool)
+}
+
+func compareSchema(ctx context.Context, q Querier) error {
+	expected, err := migrations.Version()
+	if err != nil {
+		return fmt.Errorf("read embedded migrations: %w", err)
+	}
+
+	actual, dirty, err := SchemaVersion(ctx, q)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty || actual != expected {
+		return &SchemaMismatchError{Expected: expected, Actual: actual, Dirty: dirty}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// multiTracer hands every pgx trace event to each of its tracers in order.
+// Start hooks are chained, so the context returned by one is passed to the next.
+type multiTracer []pgx.QueryTracer
+
+var (
+	_ pgx.QueryTracer    = multiTracer(nil)
+	_ pgx.BatchTracer    = multiTracer(nil)
+	_ pgx.ConnectTracer  = multiTracer(nil)
+	_ pgx.PrepareTracer  = multiTracer(nil)
+	_ pgx.CopyFromTracer = multiTracer(nil)
+
+	_ pgxpool.AcquireTracer = multiTracer(nil)
+	_ pgxpool.ReleaseTracer = multiTracer(nil)
+)
+
+func (m multiTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, t := range m {
+		ctx = t.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for _, t := range m {
+		t.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+func (m multiTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	for _, t := range m {
+		if bt, ok := t.(pgx.BatchTracer); ok {
+			ctx = bt.TraceBatchStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	for _, t := range m {
+		if bt, ok := t.(pgx.BatchTracer); ok {
+			bt.TraceBatchQuery(ctx, conn, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	for _, t := range m {
+		if bt, ok := t.(pgx.BatchTracer); ok {
+			bt.TraceBatchEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	for _, t := range m {
+		if ct, ok := t.(pgx.ConnectTracer); ok {
+			ctx = ct.TraceConnectStart(ctx, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	for _, t := range m {
+		if ct, ok := t.(pgx.ConnectTracer); ok {
+			ct.TraceConnectEnd(ctx, data)
+		}
+	}
+}
+
+func (m multiTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	for _, t := range m {
+		if pt, ok := t.(pgx.PrepareTracer); ok {
+			ctx = pt.TracePrepareStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	for _, t := range m {
+		if pt, ok := t.(pgx.PrepareTracer); ok {
+			pt.TracePrepareEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	for _, t := range m {
+		if ct, ok := t.(pgx.CopyFromTracer); ok {
+			ctx = ct.TraceCopyFromStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	for _, t := range m {
+		if ct, ok := t.(pgx.CopyFromTracer); ok {
+			ct.TraceCopyFromEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	for _, t := range m {
+		if at, ok := t.(pgxpool.AcquireTracer); ok {
+			ctx = at.TraceAcquireStart(ctx, pool, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	for _, t := range m {
+		if at, ok := t.(pgxpool.AcquireTracer); ok {
+			at.TraceAcquireEnd(ctx, pool, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceRelease(pool *pgxpool.Pool, data pgxpool.TraceReleaseData) {
+	for _, t := range m {
+		if rt, ok := t.(pgxpool.ReleaseTracer); ok {
+			rt.TraceRelease(pool, data)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+const (
+	// DefaultTxMaxAttempts is how many times a transaction runs before a retryable error is returned
+	DefaultTxMaxAttempts = 5
+	// DefaultTxBaseDelay is the backoff before the first retry, doubled on every further attempt
+	DefaultTxBaseDelay = 10 * time.Millisecond
+	// DefaultTxMaxDelay caps the backoff between two attempts
+	DefaultTxMaxDelay = time.Second
+)
+
+// SQLSTATEs worth running the whole transaction again for
+var retryableCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// TxOptions configures BeginTransaction
+type TxOptions struct {
+	IsoLevel    pgx.TxIsoLevel
+	ReadOnly    bool
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type TxOption func(o *TxOptions)
+
+// TxManager runs units of work. *DB implements it, services depend on it so they can run
+// against an in-memory fake.
+type TxManager interface {
+	BeginTransaction(ctx context.Context, f func(tx pgx.Tx, ctx context.Context) error, opts ...TxOption) error
+}
+
+var _ TxManager = (*DB)(nil)
+
+// WithIsoLevel sets the isolation level, the server default (read committed) otherwise
+func WithIsoLevel(level pgx.TxIsoLevel) TxOption {
+	return func(o *TxOptions) {
+		o.IsoLevel = level
+	}
+}
+
+// WithReadOnly starts the transaction in read only mode
+func WithReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithMaxAttempts bounds how many times a transaction runs, 1 disables retries
+func WithMaxAttempts(n int) TxOption {
+	return func(o *TxOptions) {
+		o.MaxAttempts = n
+	}
+}
+
+// WithBackoff sets the base and the maximum delay between two attempts
+func WithBackoff(base, maxDelay time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.BaseDelay = base
+		o.MaxDelay = maxDelay
+	}
+}
+
+// BeginTransaction runs f inside a transaction, committing when it returns nil.
+// The ctx handed to f carries the transaction, so repositories called with it join the unit of work.
+//
+// When ctx already carries a transaction the call is nested: f runs inside a savepoint that
+// is rolled back on its own, and the options are ignored since only the outermost call can
+// pick them or retry.
+//
+// Serialization failures and deadlocks roll the transaction back and run f again with
+// jittered exponential backoff, so f must not have side effects outside of tx.
+func (db *DB) BeginTransaction(ctx context.Context, f func(tx pgx.Tx, ctx context.Context) error, opts ...TxOption) error {
+	if parent, ok := TxFromContext(ctx); ok {
+		return db.runSavepoint(ctx, parent, f)
+	}
+
+	o := TxOptions{
+		MaxAttempts: DefaultTxMaxAttempts,
+		BaseDelay:   DefaultTxBaseDelay,
+		MaxDelay:    DefaultTxMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.MaxAttempts < 1 {
+		o.MaxAttempts = 1
+	}
+
+	txOptions := pgx.TxOptions{IsoLevel: o.IsoLevel}
+	if o.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := db.runTransaction(ctx, txOptions, f)
+		if err == nil || !IsRetryable(err) || attempt >= o.MaxAttempts {
+			return err
+		}
+
+		delay := backoff(attempt, o.BaseDelay, o.MaxDelay)
+		db.logger.Log(ctx, tracelog.LogLevelWarn, "Retrying transaction", map[string]any{
+			"attempt":      attempt,
+			"max_attempts": o.MaxAttempts,
+			"delay":        delay,
+			"err":          err,
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("Retry %w", errors.Join(ctx.Err(), err))
+		}
+	}
+}
+
+func (db *DB) runTransaction(ctx context.Context, txOptions pgx.TxOptions, f func(tx pgx.Tx, ctx context.Context) error) error {
+	tx, err := db.Pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("Begin %w", err)
+	}
+
+	return finishTransaction(ctx, tx, f)
+}
+
+// runSavepoint runs f in a pseudo nested transaction of parent, backed by a savepoint
+func (db *DB) runSavepoint(ctx context.Context, parent pgx.Tx, f func(tx pgx.Tx, ctx context.Context) error) error {
+	tx, err := parent.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Savepoint %w", err)
+	}
+
+	return finishTransaction(ctx, tx, f)
+}
+
+func finishTransaction(ctx context.Context, tx pgx.Tx, f func(tx pgx.Tx, ctx context.Context) error) error {
+	txCtx := WithTx(ctx, tx)
+	if err := f(tx, txCtx); err != nil {
+		_ = tx.Rollback(ctx)
+
+		return fmt.Errorf("f %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Commit %w", err)
+	}
+
+	return nil
+}
+
+// IsRetryable reports whether err is a transient failure that a new attempt may not hit
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return retryableCodes[pgErr.Code]
+}
+
+// backoff returns a random delay in [0, min(maxDelay, base*2^(attempt-1))] (full jitter)
+func backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base << (attempt - 1)
+	if delay > maxDelay || delay <= 0 {
+		delay = maxDelay
+	}
+
+	return rand.N(delay + 1)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	serialization := fmt.Errorf("f %w", &pgconn.PgError{Code: "40001"})
+	deadlock := fmt.Errorf("Commit %w", &pgconn.PgError{Code: "40P01"})
+	unique := fmt.Errorf("f %w", &pgconn.PgError{Code: "23505"})
+
+	assert.True(t, IsRetryable(serialization))
+	assert.True(t, IsRetryable(deadlock))
+	assert.False(t, IsRetryable(unique))
+	assert.False(t, IsRetryable(errors.New("40001")))
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := backoff(attempt, 10*time.Millisecond, 100*time.Millisecond)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 100*time.Millisecond)
+	}
+	assert.LessOrEqual(t, backoff(1, 10*time.Millisecond, time.Second), 10*time.Millisecond)
+	assert.Equal(t, time.Duration(0), backoff(3, 0, time.Second))
+}
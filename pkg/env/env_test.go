@@ -0,0 +1,18 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnvInt(t *testing.T) {
+	t.Setenv("TEST_INT", "42")
+	v, err := GetEnvInt("TEST_INT")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	t.Setenv("TEST_INT", "forty-two")
+	_, err = GetEnvInt("TEST_INT")
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"goroutines/pkg/database"
+	"net/http"
+)
+
+// Pinger is implemented by *database.DB and *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping fails when p can't be reached before the check deadline
+func Ping(p Pinger) CheckFunc {
+	return p.Ping
+}
+
+// PoolSaturation fails when the primary pool has more than threshold (0 to 1) of its
+// connections acquired, new requests would then wait for a connection
+func PoolSaturation(db *database.DB, threshold float64) CheckFunc {
+	return func(context.Context) error {
+		stat := db.PoolStats()[0]
+		if stat.Max == 0 {
+			return nil
+		}
+
+		saturation := float64(stat.Acquired) / float64(stat.Max)
+		if saturation > threshold {
+			return fmt.Errorf("pool %s saturated: %d/%d connections acquired", stat.Name, stat.Acquired, stat.Max)
+		}
+		return nil
+	}
+}
+
+// Schema fails when the schema version drifted from the embedded migrations
+func Schema(db *database.DB) CheckFunc {
+	return db.CheckSchema
+}
+
+// HTTP fails when url can't be reached or answers with a server error
+func HTTP(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		if res.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s answered %d", url, res.StatusCode)
+		}
+		return nil
+	}
+}
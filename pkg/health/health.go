@@ -0,0 +1,162 @@
+// Package health runs readiness checks concurrently and reports their outcome.
+package health
+
+import (
+	"context"
+	"errors"
+	"goroutines/util/conc"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusWarn is a failed optional check, it does not fail readiness
+	StatusWarn = "warn"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+// CheckFunc reports a dependency as unhealthy by returning an error. It must honour ctx.
+type CheckFunc func(ctx context.Context) error
+
+// Check is a named readiness check
+type Check struct {
+	Name string
+	Fn   CheckFunc
+	// Timeout overrides the default deadline of the check
+	Timeout time.Duration
+	// Optional checks are reported without failing readiness
+	Optional bool
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Optional   bool    `json:"optional,omitempty"`
+}
+
+// Report is the readiness breakdown
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether every required check passed
+func (r *Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Health holds the readiness checks, it reports not ready for good once shutdown started
+type Health struct {
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+
+	mu     sync.RWMutex
+	checks []Check
+}
+
+// New returns a Health running each check with timeout unless the check sets its own
+func New(timeout time.Duration, checks ...Check) *Health {
+	return &Health{timeout: timeout, checks: checks}
+}
+
+// Add registers more checks
+func (h *Health) Add(checks ...Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, checks...)
+}
+
+// Shutdown flips readiness to failing, so load balancers stop routing new requests here
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs every check concurrently, each under its own deadline, and reports the outcome
+func (h *Health) Ready(ctx context.Context) *Report {
+	h.mu.RLock()
+	checks := append([]Check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	// Checks not started because ctx is done fail with its error
+	ran := conc.Map(ctx, checks, 0, func(ctx context.Context, c Check) (Result, error) {
+		return h.run(ctx, c), nil
+	})
+	results := make([]Result, len(checks))
+	for i, r := range ran {
+		results[i] = r.Result
+		if r.Error != nil {
+			results[i] = failed(checks[i], r.Error, 0)
+		}
+	}
+
+	report := &Report{Status: StatusOK, Checks: results}
+	if h.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks = append(report.Checks, Result{Name: "shutdown", Status: StatusFail, Error: ErrShuttingDown.Error()})
+	}
+	for _, r := range results {
+		if r.Status == StatusFail {
+			report.Status = StatusFail
+		}
+	}
+	sort.Slice(report.Checks, func(i, j int) bool { return report.Checks[i].Name < report.Checks[j].Name })
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, c Check) Result {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = h.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	// A check ignoring ctx still can't hold the report past its deadline
+	done := make(chan error, 1)
+	go func() { done <- c.Fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return failed(c, err, time.Since(start))
+	}
+
+	return Result{
+		Name:       c.Name,
+		Status:     StatusOK,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+		Optional:   c.Optional,
+	}
+}
+
+// failed reports c as failing with err, a warning when c is optional
+func failed(c Check, err error, took time.Duration) Result {
+	status := StatusFail
+	if c.Optional {
+		status = StatusWarn
+	}
+
+	return Result{
+		Name:       c.Name,
+		Status:     status,
+		Error:      err.Error(),
+		DurationMs: float64(took) / float64(time.Millisecond),
+		Optional:   c.Optional,
+	}
+}
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		// Ignores ctx on purpose, the deadline must still hold
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}
+	failing := func(context.Context) error { return errors.New("unreachable") }
+	ok := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		shutdown   bool
+		wantReady  bool
+		wantStatus map[string]string
+	}{
+		{
+			name:       "every check passes",
+			checks:     []Check{{Name: "db", Fn: ok}, {Name: "schema", Fn: ok}},
+			wantReady:  true,
+			wantStatus: map[string]string{"db": StatusOK, "schema": StatusOK},
+		},
+		{
+			name:       "failing required check",
+			checks:     []Check{{Name: "db", Fn: failing}, {Name: "schema", Fn: ok}},
+			wantStatus: map[string]string{"db": StatusFail, "schema": StatusOK},
+		},
+		{
+			name:       "failing optional check only warns",
+			checks:     []Check{{Name: "db", Fn: ok}, {Name: "ipapi", Fn: failing, Optional: true}},
+			wantReady:  true,
+			wantStatus: map[string]string{"db": StatusOK, "ipapi": StatusWarn},
+		},
+		{
+			name:       "check past its deadline",
+			checks:     []Check{{Name: "db", Fn: slow, Timeout: 20 * time.Millisecond}},
+			wantStatus: map[string]string{"db": StatusFail},
+		},
+		{
+			name:       "shutting down",
+			checks:     []Check{{Name: "db", Fn: ok}},
+			shutdown:   true,
+			wantStatus: map[string]string{"db": StatusOK, "shutdown": StatusFail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(time.Second, tt.checks...)
+			if tt.shutdown {
+				h.Shutdown()
+			}
+
+			start := time.Now()
+			report := h.Ready(context.Background())
+
+			assert.Less(t, time.Since(start), 150*time.Millisecond)
+			assert.Equal(t, tt.wantReady, report.Ready())
+			status := map[string]string{}
+			for _, r := range report.Checks {
+				status[r.Name] = r.Status
+			}
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestReadyRunsConcurrently(t *testing.T) {
+	wait := func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	h := New(time.Second, Check{Name: "a", Fn: wait}, Check{Name: "b", Fn: wait}, Check{Name: "c", Fn: wait})
+
+	start := time.Now()
+	assert.True(t, h.Ready(context.Background()).Ready())
+	assert.Less(t, time.Since(start), 120*time.Millisecond)
+}
@@ -2,10 +2,13 @@ package ipapi
 
 import (
 	"encoding/json"
-	"fmt"
 	"goroutines/pkg/api"
+	"log/slog"
 )
 
+// BaseURL is the ipapi.co endpoint used by Request
+const BaseURL = "https://ipapi.co"
+
 type Response struct {
 	Asn                string      `json:"asn"`
 	City               string      `json:"city"`
@@ -37,15 +40,15 @@ type Response struct {
 }
 
 func Request() (*Response, error) {
-	client, err := api.NewClient("https://ipapi.co")
+	client, err := api.NewClient(BaseURL)
 	if err != nil {
-		fmt.Printf("Client error: %s\n", err)
+		slog.Error("ipapi client error", slog.Any("error", err))
 		return nil, err
 	}
 
 	req, err := client.NewRequest("GET", "/json", nil)
 	if err != nil {
-		fmt.Printf("Request error: %s\n", err)
+		slog.Error("ipapi request error", slog.Any("error", err))
 		return nil, err
 	}
 
@@ -0,0 +1,126 @@
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rampTick is how often the number of running virtual users is adjusted
+const rampTick = 100 * time.Millisecond
+
+// Stage ramps the virtual users linearly from the previous target to Target over Duration
+type Stage struct {
+	Target   int
+	Duration time.Duration
+}
+
+// K6Stages is the ramp of scripts/k6/script.js in load test mode
+var K6Stages = []Stage{
+	{Target: 50, Duration: 5 * time.Second},
+	{Target: 100, Duration: 10 * time.Second},
+	{Target: 150, Duration: 20 * time.Second},
+	{Target: 200, Duration: 20 * time.Second},
+	{Target: 250, Duration: 20 * time.Second},
+	{Target: 300, Duration: 20 * time.Second},
+	{Target: 600, Duration: 20 * time.Second},
+	{Target: 1200, Duration: 20 * time.Second},
+}
+
+// Scale returns stages with every duration multiplied by factor, for shorter runs
+func Scale(stages []Stage, factor float64) []Stage {
+	scaled := make([]Stage, len(stages))
+	for i, s := range stages {
+		scaled[i] = Stage{s.Target, time.Duration(float64(s.Duration) * factor)}
+	}
+
+	return scaled
+}
+
+// Options configures Run
+type Options struct {
+	Stages []Stage
+	// Requests is the number of requests a virtual user sends per iteration
+	Requests int
+	// Think is the pause of a virtual user between two iterations
+	Think time.Duration
+}
+
+// Request sends one request, a non nil error counts as a failed request
+type Request func(ctx context.Context) error
+
+// Run replays the stages like k6 ramping VUs: every virtual user loops over iterations of
+// opts.Requests calls to do followed by opts.Think, until it is ramped down or the last stage ends.
+// Requests in flight when a user is ramped down are allowed to finish.
+func Run(ctx context.Context, opts Options, do Request) *Report {
+	if opts.Requests < 1 {
+		opts.Requests = 1
+	}
+
+	rec := newRecorder()
+	var (
+		wg    sync.WaitGroup
+		users []context.CancelFunc
+	)
+	scale := func(target int) {
+		for len(users) < target {
+			vuCtx, stop := context.WithCancel(ctx)
+			users = append(users, stop)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				virtualUser(vuCtx, opts, do, rec)
+			}()
+		}
+		for len(users) > target {
+			users[len(users)-1]()
+			users = users[:len(users)-1]
+		}
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(rampTick)
+	defer ticker.Stop()
+
+	from := 0
+ramp:
+	for _, stage := range opts.Stages {
+		stageStart := time.Now()
+		for elapsed := time.Duration(0); elapsed < stage.Duration; elapsed = time.Since(stageStart) {
+			progress := float64(elapsed) / float64(stage.Duration)
+			scale(from + int(float64(stage.Target-from)*progress))
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break ramp
+			}
+		}
+		from = stage.Target
+	}
+
+	scale(0)
+	wg.Wait()
+
+	return rec.report(time.Since(start))
+}
+
+func virtualUser(ctx context.Context, opts Options, do Request, rec *recorder) {
+	for ctx.Err() == nil {
+		for range opts.Requests {
+			// The request outlives the ramp down, like a k6 graceful stop
+			begin := time.Now()
+			err := do(context.WithoutCancel(ctx))
+			rec.record(time.Since(begin), err)
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-time.After(opts.Think):
+		case <-ctx.Done():
+		}
+	}
+}
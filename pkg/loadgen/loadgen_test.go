@@ -0,0 +1,60 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var (
+		calls   atomic.Int64
+		running atomic.Int64
+		peak    atomic.Int64
+	)
+	do := func(ctx context.Context) error {
+		n := calls.Add(1)
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); cur > p && !peak.CompareAndSwap(p, cur); p = peak.Load() {
+		}
+
+		time.Sleep(time.Millisecond)
+		if n%10 == 0 {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	report := Run(context.Background(), Options{
+		Stages:   []Stage{{Target: 5, Duration: 300 * time.Millisecond}, {Target: 10, Duration: 300 * time.Millisecond}},
+		Requests: 2,
+		Think:    10 * time.Millisecond,
+	}, do)
+
+	assert.Equal(t, int(calls.Load()), report.Requests)
+	assert.Equal(t, report.Requests/10, report.Errors)
+	assert.LessOrEqual(t, peak.Load(), int64(10))
+	assert.Zero(t, running.Load(), "every virtual user stopped")
+	assert.GreaterOrEqual(t, report.P99Ms, report.P50Ms)
+	assert.GreaterOrEqual(t, report.P50Ms, 1.0)
+
+	var md bytes.Buffer
+	assert.NoError(t, WriteMarkdown(&md, []*Report{report}))
+	assert.Contains(t, md.String(), "| Strategy |")
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	Run(ctx, Options{Stages: K6Stages, Think: time.Second}, func(context.Context) error { return nil })
+
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,102 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report summarizes the requests of one Run. Latencies are in milliseconds.
+type Report struct {
+	Name       string  `json:"name"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"`
+	DurationS  float64 `json:"duration_s"`
+	Throughput float64 `json:"throughput_rps"`
+	MeanMs     float64 `json:"mean_ms"`
+	P50Ms      float64 `json:"p50_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	MaxMs      float64 `json:"max_ms"`
+}
+
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+}
+
+func newRecorder() *recorder {
+	return &recorder{latencies: make([]time.Duration, 0, 1<<16)}
+}
+
+func (r *recorder) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+	if err != nil {
+		r.errors++
+	}
+}
+
+func (r *recorder) report(elapsed time.Duration) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		Requests:  len(r.latencies),
+		Errors:    r.errors,
+		DurationS: elapsed.Seconds(),
+	}
+	if report.Requests == 0 {
+		return report
+	}
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	var total time.Duration
+	for _, l := range r.latencies {
+		total += l
+	}
+
+	report.ErrorRate = float64(r.errors) / float64(report.Requests)
+	report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	report.MeanMs = ms(total / time.Duration(report.Requests))
+	report.P50Ms = ms(percentile(r.latencies, 0.50))
+	report.P95Ms = ms(percentile(r.latencies, 0.95))
+	report.P99Ms = ms(percentile(r.latencies, 0.99))
+	report.MaxMs = ms(r.latencies[len(r.latencies)-1])
+
+	return report
+}
+
+// WriteMarkdown writes the reports as a Markdown comparison table, one row per report
+func WriteMarkdown(w io.Writer, reports []*Report) error {
+	if _, err := fmt.Fprintln(w, "| Strategy | Requests | Errors | Error rate | Throughput (req/s) | p50 (ms) | p95 (ms) | p99 (ms) |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|"); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		_, err := fmt.Fprintf(w, "| %s | %d | %d | %.2f%% | %.1f | %.1f | %.1f | %.1f |\n",
+			r.Name, r.Requests, r.Errors, r.ErrorRate*100, r.Throughput, r.P50Ms, r.P95Ms, r.P99Ms)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// percentile of sorted latencies, nearest rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,50 @@
+// Package logging builds the process logger and carries the request scoped logger in contexts.
+package logging
+
+import (
+	"context"
+	"goroutines/config"
+	"io"
+	"log/slog"
+)
+
+// RequestIDKey is the attribute of the request ID on every log line of a request
+const RequestIDKey = "request_id"
+
+type loggerKey struct{}
+
+// New returns a logger writing to w in the configured format and level, scrubbing secrets
+func New(w io.Writer, cfg *config.Log) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if cfg.Format == config.LogFormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(NewRedactHandler(
+		handler,
+		append(DefaultRedactKeys, cfg.RedactKeys...),
+		DefaultRedactPatterns,
+	))
+}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Lookup returns the logger carried by ctx, if any
+func Lookup(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger, ok
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default() outside of a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := Lookup(ctx); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
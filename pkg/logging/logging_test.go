@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"goroutines/config"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &config.Log{Format: config.LogFormatJSON, Level: slog.LevelWarn})
+
+	logger.Info("dropped")
+	logger.Warn("kept", slog.String("password", "hunter2"))
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "kept", line["msg"])
+	assert.Equal(t, Redacted, line["password"])
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	logger := slog.Default().With(slog.String(RequestIDKey, "req-1"))
+	assert.Same(t, logger, FromContext(WithLogger(context.Background(), logger)))
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil), DefaultRedactKeys, DefaultRedactPatterns))
+
+	logger.With(slog.String("token", "abc")).Info("connect postgres://app:hunter2@db:5432/app",
+		slog.String("Password", "hunter2"),
+		slog.Group("req", slog.String("authorization", "Bearer xyz")),
+		slog.Any("error", errors.New("dial postgres://app:hunter2@db failed")),
+		slog.String("header", "Bearer xyz.123"),
+	)
+
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "abc")
+	assert.NotContains(t, out, "xyz")
+	assert.Contains(t, out, "postgres://app:"+Redacted+"@db")
+	assert.Contains(t, out, "Password="+Redacted)
+}
@@ -0,0 +1,74 @@
+// Package metrics exposes the process, HTTP and database pool metrics in the Prometheus format.
+package metrics
+
+import (
+	"goroutines/pkg/database"
+	"goroutines/util"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goroutines"
+
+// Metrics owns a registry with the collectors of this service
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New registers the HTTP, runtime and service goroutine collectors, and the pool collector
+// when db is not nil (memory storage)
+func New(db *database.DB) *Metrics {
+	labels := []string{"route", "method", "status", "strategy"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method, status and create strategy.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method, status and create strategy.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, labels),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		// go_goroutines plus the runtime/metrics scheduler latencies (go_sched_latencies_seconds)
+		collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsScheduler)),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "service_goroutines_inflight",
+			Help:      "Goroutines started through util.Go, util.GoInto and util.GoSafe still running.",
+		}, func() float64 { return float64(util.Running()) }),
+	)
+	if db != nil {
+		m.registry.MustRegister(newPoolCollector(db))
+	}
+
+	return m
+}
+
+// ObserveRequest records one served request, strategy is empty outside the create endpoint
+func (m *Metrics) ObserveRequest(route, method string, status int, strategy string, elapsed time.Duration) {
+	values := []string{route, method, strconv.Itoa(status), strategy}
+	m.requests.WithLabelValues(values...).Inc()
+	m.duration.WithLabelValues(values...).Observe(elapsed.Seconds())
+}
+
+// Handler serves the registry in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"goroutines/pkg/database"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads db.PoolStats on every scrape, one series per pool
+type poolCollector struct {
+	db *database.DB
+
+	acquired         *prometheus.Desc
+	idle             *prometheus.Desc
+	constructing     *prometheus.Desc
+	total            *prometheus.Desc
+	max              *prometheus.Desc
+	acquireCount     *prometheus.Desc
+	waitCount        *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+}
+
+func newPoolCollector(db *database.DB) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, []string{"pool"}, nil)
+	}
+
+	return &poolCollector{
+		db:               db,
+		acquired:         desc("acquired_conns", "Connections currently acquired."),
+		idle:             desc("idle_conns", "Connections currently idle."),
+		constructing:     desc("constructing_conns", "Connections being established."),
+		total:            desc("total_conns", "Connections open, acquired, idle and constructing."),
+		max:              desc("max_conns", "Maximum size of the pool."),
+		acquireCount:     desc("acquires_total", "Successful acquires."),
+		waitCount:        desc("empty_acquires_total", "Acquires that waited for a connection."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires canceled by their context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.constructing
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquireCount
+	ch <- c.waitCount
+	ch <- c.acquireDuration
+	ch <- c.canceledAcquires
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.db.PoolStats() {
+		ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.Acquired), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.constructing, prometheus.GaugeValue, float64(s.Constructing), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.Total), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.Max), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration.Seconds(), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquires), s.Name)
+	}
+}
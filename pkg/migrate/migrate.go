@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goroutines/config"
+	"goroutines/db/migrations"
+	"log/slog"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+)
+
+// lockKey is the pg_advisory_lock key serializing MIGRATE_ON_START across replicas
+const lockKey int64 = 7_318_042_517
+
+// Migrator applies the embedded migrations to the configured database
+type Migrator struct {
+	m   *migrate.Migrate
+	url string
+}
+
+func New(cfg *config.DB) (*Migrator, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("open embedded migrations: %w", err)
+	}
+
+	url := connString(cfg, "pgx5")
+	m, err := migrate.NewWithSourceInstance("iofs", src, url)
+	if err != nil {
+		return nil, fmt.Errorf("init migrate: %w", err)
+	}
+	m.Log = &logger{slog.Default()}
+
+	return &Migrator{m, connString(cfg, "postgres")}, nil
+}
+
+// Up applies every pending migration
+func (mg *Migrator) Up() error {
+	return ignoreNoChange(mg.m.Up())
+}
+
+// Down rolls back n migrations, all of them when n <= 0
+func (mg *Migrator) Down(n int) error {
+	if n <= 0 {
+		return ignoreNoChange(mg.m.Down())
+	}
+
+	return ignoreNoChange(mg.m.Steps(-n))
+}
+
+// Steps applies n migrations up, or rolls back -n of them when n is negative
+func (mg *Migrator) Steps(n int) error {
+	return ignoreNoChange(mg.m.Steps(n))
+}
+
+// Goto migrates up or down to version
+func (mg *Migrator) Goto(version uint) error {
+	return ignoreNoChange(mg.m.Migrate(version))
+}
+
+// Version returns the current version, and whether the last migration failed half way
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// Force sets the version without running anything, clearing the dirty flag
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+// UpLocked applies every pending migration while holding a session advisory lock, so replicas
+// starting together run them once and the others wait for the schema to be ready.
+func (mg *Migrator) UpLocked(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, mg.url)
+	if err != nil {
+		return fmt.Errorf("connect for migration lock: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	return mg.Up()
+}
+
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+func connString(cfg *config.DB, scheme string) string {
+	return fmt.Sprintf("%s://%s:%s@%s:%d/%s?%s",
+		scheme,
+		cfg.Username,
+		cfg.Pass,
+		cfg.Host,
+		cfg.Port,
+		cfg.Name,
+		cfg.Params,
+	)
+}
+
+// logger forwards golang-migrate logs to slog
+type logger struct {
+	*slog.Logger
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	l.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l *logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"goroutines/config"
+	"goroutines/db/migrations"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaDump lists every object of the public schema in a stable order, so two states of the
+// schema can be compared without pg_dump. schema_migrations is left out, its version moves.
+const schemaDump = `
+SELECT 'extension ' || extname
+FROM pg_extension
+WHERE extname <> 'plpgsql'
+UNION ALL
+SELECT format('column %s.%s %s notnull=%s default=%s',
+	c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+	pg_get_expr(d.adbin, d.adrelid))
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = 'public' AND c.relkind = 'r' AND a.attnum > 0 AND NOT a.attisdropped
+	AND c.relname <> 'schema_migrations'
+UNION ALL
+SELECT format('constraint %s.%s %s', c.relname, con.conname, pg_get_constraintdef(con.oid))
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public' AND c.relname <> 'schema_migrations'
+UNION ALL
+SELECT 'index ' || indexdef
+FROM pg_indexes
+WHERE schemaname = 'public' AND tablename <> 'schema_migrations'
+UNION ALL
+SELECT 'trigger ' || pg_get_triggerdef(t.oid)
+FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public' AND NOT t.tgisinternal
+ORDER BY 1`
+
+// Needs a local Postgres whose user may create databases, e.g.:
+//
+//	DB_HOST=localhost DB_PORT=5432 DB_USERNAME=postgres DB_PASSWORD=postgres \
+//	DB_NAME=postgres DB_PARAMS=sslmode=disable go test ./pkg/migrate
+func TestMigrationsRoundTrip(t *testing.T) {
+	cfg := scratchDatabase(t)
+	ctx := context.Background()
+
+	m, err := New(cfg)
+	require.NoError(t, err)
+	defer m.Close()
+
+	conn, err := pgx.Connect(ctx, connString(cfg, "postgres"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	before := dumpSchema(t, conn)
+	for _, version := range upVersions(t) {
+		require.NoError(t, m.Steps(1), "up %d", version)
+		after := dumpSchema(t, conn)
+
+		require.NoError(t, m.Steps(-1), "down %d", version)
+		assert.Equal(t, before, dumpSchema(t, conn), "down %d doesn't undo its up", version)
+
+		require.NoError(t, m.Steps(1), "up %d again", version)
+		assert.Equal(t, after, dumpSchema(t, conn), "up %d doesn't give the same schema twice", version)
+
+		before = after
+	}
+}
+
+func TestSeederIdempotent(t *testing.T) {
+	cfg := scratchDatabase(t)
+	ctx := context.Background()
+
+	m, err := New(cfg)
+	require.NoError(t, err)
+	defer m.Close()
+
+	seeders, err := fs.Glob(migrations.FS, "*_create_seeder.up.sql")
+	require.NoError(t, err)
+	require.Len(t, seeders, 1)
+	prefix, _, _ := strings.Cut(seeders[0], "_")
+	seeder, err := strconv.ParseUint(prefix, 10, 64)
+	require.NoError(t, err)
+	require.NoError(t, m.Goto(uint(seeder)))
+
+	conn, err := pgx.Connect(ctx, connString(cfg, "postgres"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	categories := func() []string {
+		rows, err := conn.Query(ctx, `SELECT name FROM categories WHERE deleted_at IS NULL ORDER BY name`)
+		require.NoError(t, err)
+		names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		require.NoError(t, err)
+		return names
+	}
+
+	// A category added after seeding must survive the seeder being rolled back
+	_, err = conn.Exec(ctx, `INSERT INTO categories (name) VALUES ('Books')`)
+	require.NoError(t, err)
+	seeded := categories()
+	require.Greater(t, len(seeded), 1)
+
+	for run := range 2 {
+		require.NoError(t, m.Steps(-1), "down %d, run %d", seeder, run)
+		assert.Equal(t, []string{"Books"}, categories(), "down %d left seeded rows behind", seeder)
+
+		require.NoError(t, m.Steps(1), "%s is not idempotent, run %d", seeders[0], run)
+		assert.Equal(t, seeded, categories())
+	}
+}
+
+// scratchDatabase creates an empty database for the test, dropped on cleanup
+func scratchDatabase(t *testing.T) *config.DB {
+	t.Helper()
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST not set, skipping migration test")
+	}
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, connString(cfg.DB, "postgres"))
+	require.NoError(t, err)
+
+	scratch := *cfg.DB
+	scratch.Name = fmt.Sprintf("goroutines_migrate_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, `CREATE DATABASE `+pgx.Identifier{scratch.Name}.Sanitize())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		defer admin.Close(ctx)
+		_, err := admin.Exec(ctx, `DROP DATABASE IF EXISTS `+pgx.Identifier{scratch.Name}.Sanitize()+` WITH (FORCE)`)
+		assert.NoError(t, err)
+	})
+
+	return &scratch
+}
+
+func dumpSchema(t *testing.T, conn *pgx.Conn) string {
+	t.Helper()
+
+	rows, err := conn.Query(context.Background(), schemaDump)
+	require.NoError(t, err)
+	lines, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err)
+
+	return strings.Join(lines, "\n")
+}
+
+func upVersions(t *testing.T) []uint64 {
+	t.Helper()
+
+	ups, err := fs.Glob(migrations.FS, "*.up.sql")
+	require.NoError(t, err)
+
+	versions := make([]uint64, 0, len(ups))
+	for _, up := range ups {
+		prefix, _, _ := strings.Cut(up, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		require.NoError(t, err)
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	return versions
+}
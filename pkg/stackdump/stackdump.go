@@ -0,0 +1,144 @@
+// Package stackdump captures the stacks of every goroutine and groups identical ones, so thousands
+// of goroutines parked on the same line read as a single entry with a count.
+package stackdump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Group is a set of goroutines sharing the same stack
+type Group struct {
+	Count int `json:"count"`
+	// States counts the goroutines of the group by scheduling state, "chan receive" or "running"
+	States map[string]int `json:"states"`
+	// Stack lists the frames as "function file:line", innermost first, the creator last
+	Stack []string `json:"stack"`
+}
+
+// Dump is every goroutine of the process grouped by stack, largest groups first
+type Dump struct {
+	Total  int     `json:"total"`
+	Groups []Group `json:"groups"`
+}
+
+// Capture stops the world to collect the stacks of all the goroutines and groups them
+func Capture() *Dump {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return Parse(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Parse groups the goroutines of a runtime.Stack(buf, true) output. Goroutine IDs, wait
+// durations, arguments and pc offsets are dropped, so only the frames tell stacks apart.
+func Parse(stacks []byte) *Dump {
+	dump := &Dump{}
+	groups := map[string]*Group{}
+	var order []string
+
+	for _, block := range bytes.Split(bytes.TrimSpace(stacks), []byte("\n\n")) {
+		lines := strings.Split(string(block), "\n")
+		state, ok := parseHeader(lines[0])
+		if !ok {
+			continue
+		}
+		frames := parseFrames(lines[1:])
+
+		key := strings.Join(frames, "\n")
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{States: map[string]int{}, Stack: frames}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.Count++
+		g.States[state]++
+		dump.Total++
+	}
+
+	dump.Groups = make([]Group, 0, len(order))
+	for _, key := range order {
+		dump.Groups = append(dump.Groups, *groups[key])
+	}
+	sort.SliceStable(dump.Groups, func(i, j int) bool {
+		return dump.Groups[i].Count > dump.Groups[j].Count
+	})
+
+	return dump
+}
+
+// WriteText writes the groups in a compact text form, one header line and the frames per group
+func (d *Dump) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%d goroutines, %d distinct stacks\n", d.Total, len(d.Groups)); err != nil {
+		return err
+	}
+
+	for _, g := range d.Groups {
+		states := make([]string, 0, len(g.States))
+		for state, n := range g.States {
+			states = append(states, fmt.Sprintf("%s: %d", state, n))
+		}
+		sort.Strings(states)
+
+		if _, err := fmt.Fprintf(w, "\n%d [%s]\n", g.Count, strings.Join(states, ", ")); err != nil {
+			return err
+		}
+		for _, frame := range g.Stack {
+			if _, err := fmt.Fprintf(w, "\t%s\n", frame); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseHeader reads the state of "goroutine 18 [chan receive, 2 minutes]:"
+func parseHeader(line string) (string, bool) {
+	if !strings.HasPrefix(line, "goroutine ") {
+		return "", false
+	}
+
+	start, end := strings.IndexByte(line, '['), strings.LastIndexByte(line, ']')
+	if start < 0 || end < start {
+		return "", false
+	}
+	state, _, _ := strings.Cut(line[start+1:end], ",")
+
+	return state, true
+}
+
+// parseFrames pairs each function line with its file line
+func parseFrames(lines []string) []string {
+	frames := make([]string, 0, len(lines)/2)
+	for i := 0; i < len(lines); i++ {
+		fn := lines[i]
+		if strings.HasPrefix(fn, "created by ") {
+			// "created by main.main in goroutine 1"
+			fn, _, _ = strings.Cut(fn, " in goroutine ")
+		} else if open := strings.LastIndexByte(fn, '('); open > 0 {
+			// "main.worker(0xc000010000, ...)"
+			fn = fn[:open]
+		}
+
+		file := ""
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			i++
+			// "\t/path/main.go:20 +0x45"
+			file, _, _ = strings.Cut(strings.TrimPrefix(lines[i], "\t"), " +0x")
+		}
+
+		frames = append(frames, strings.TrimSpace(fn+" "+file))
+	}
+
+	return frames
+}
@@ -0,0 +1,88 @@
+package stackdump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x25
+
+goroutine 18 [chan receive, 2 minutes]:
+main.worker(0xc000010000)
+	/app/main.go:20 +0x45
+created by main.main in goroutine 1
+	/app/main.go:12 +0x65
+
+goroutine 19 [chan receive]:
+main.worker(0xc000010008)
+	/app/main.go:20 +0x45
+created by main.main in goroutine 1
+	/app/main.go:12 +0x65
+
+goroutine 20 [select]:
+main.worker(0xc000010010)
+	/app/main.go:20 +0x45
+created by main.main in goroutine 1
+	/app/main.go:12 +0x65
+`
+
+func TestParse(t *testing.T) {
+	dump := Parse([]byte(sample))
+
+	assert.Equal(t, 4, dump.Total)
+	require.Len(t, dump.Groups, 2)
+
+	workers := dump.Groups[0]
+	assert.Equal(t, 3, workers.Count)
+	assert.Equal(t, map[string]int{"chan receive": 2, "select": 1}, workers.States)
+	assert.Equal(t, []string{"main.worker /app/main.go:20", "created by main.main /app/main.go:12"}, workers.Stack)
+
+	assert.Equal(t, Group{Count: 1, States: map[string]int{"running": 1}, Stack: []string{"main.main /app/main.go:10"}}, dump.Groups[1])
+
+	var buf bytes.Buffer
+	require.NoError(t, dump.WriteText(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "4 goroutines, 2 distinct stacks\n\n3 [chan receive: 2, select: 1]\n"))
+}
+
+func parked(ready chan<- struct{}, release <-chan struct{}) {
+	ready <- struct{}{}
+	<-release
+}
+
+func TestCapture(t *testing.T) {
+	const n = 25
+	ready, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	for range n {
+		go parked(ready, release)
+	}
+	for range n {
+		<-ready
+	}
+
+	// Signaling ready doesn't mean parked yet, wait until all of them block on release
+	var group *Group
+	require.Eventually(t, func() bool {
+		group = parkedGroup(Capture())
+		return group != nil && group.States["chan receive"] == n
+	}, 5*time.Second, time.Millisecond, "%d goroutines never parked in stackdump.parked", n)
+
+	assert.Equal(t, n, group.Count)
+}
+
+// parkedGroup finds the goroutines blocked on the release channel of parked
+func parkedGroup(dump *Dump) *Group {
+	for i, g := range dump.Groups {
+		if strings.Contains(g.Stack[0], "stackdump.parked") && g.States["chan receive"] > 0 {
+			return &dump.Groups[i]
+		}
+	}
+	return nil
+}
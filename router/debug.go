@@ -0,0 +1,35 @@
+package router
+
+import (
+	"goroutines/config"
+	"goroutines/internal/system/controller"
+	"goroutines/router/middleware"
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerDebug serves pprof, runtime traces and goroutine dumps under /debug, guarded like
+// every admin route
+func registerDebug(cfg *config.App, router *gin.Engine) {
+	debug, ok := middleware.AdminGroup(router, "/debug", cfg)
+	if !ok {
+		return
+	}
+
+	dump := controller.NewDebugController()
+	debug.GET("/goroutines", dump.Goroutines)
+
+	profiles := debug.Group("/pprof")
+	{
+		profiles.GET("/", gin.WrapF(pprof.Index))
+		profiles.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		profiles.GET("/profile", gin.WrapF(pprof.Profile))
+		profiles.GET("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.POST("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.GET("/trace", dump.Trace)
+		for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+			profiles.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"goroutines/config"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrAdminToken is reported when a guarded route is called without the admin token
+var ErrAdminToken = errors.New("A valid admin token is required")
+
+func init() {
+	RegisterStatus(ErrAdminToken, http.StatusUnauthorized)
+}
+
+// AdminToken rejects the requests not carrying "Authorization: Bearer <token>", all of them when token is empty
+func AdminToken(token string) gin.HandlerFunc {
+	expected := []byte(token)
+
+	return func(ctx *gin.Context) {
+		given, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || len(expected) == 0 || subtle.ConstantTimeCompare([]byte(given), expected) != 1 {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.Error(ErrAdminToken)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// AdminGroup mounts the admin routes at path. They are open outside production, and need the
+// admin token whenever it is set. Production without a token doesn't serve them, ok is false.
+func AdminGroup(parent gin.IRouter, path string, cfg *config.App) (group *gin.RouterGroup, ok bool) {
+	if cfg.IsProduction() && cfg.AdminToken == "" {
+		return nil, false
+	}
+
+	group = parent.Group(path)
+	if cfg.AdminToken != "" {
+		group.Use(AdminToken(cfg.AdminToken))
+	}
+
+	return group, true
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"goroutines/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{"valid", "s3cret", "Bearer s3cret", http.StatusNoContent},
+		{"missing", "s3cret", "", http.StatusUnauthorized},
+		{"wrong", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "s3cret", "s3cret", http.StatusUnauthorized},
+		{"empty token rejects everything", "", "Bearer ", http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler(), AdminToken(tc.token))
+			router.GET("/debug", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/debug", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestAdminGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		app    config.App
+		header string
+		status int
+	}{
+		{"open without a token", config.App{}, "", http.StatusNoContent},
+		{"guarded with a token", config.App{AdminToken: "s3cret"}, "", http.StatusUnauthorized},
+		{"token given", config.App{AdminToken: "s3cret"}, "Bearer s3cret", http.StatusNoContent},
+		{"production guarded", config.App{Env: config.EnvProduction, AdminToken: "s3cret"}, "", http.StatusUnauthorized},
+		{"production without a token not served", config.App{Env: config.EnvProduction}, "", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			if admin, ok := AdminGroup(router, "/admin", &tc.app); ok {
+				admin.PUT("/strategy", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/strategy", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
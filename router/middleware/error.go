@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"errors"
+	dbErrs "goroutines/pkg/database/errs"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body written for every failed request
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// StatusMapping pairs a sentinel error with the status code of the responses it causes
+type StatusMapping struct {
+	Err    error
+	Status int
+}
+
+var (
+	mappingsMu sync.RWMutex
+	// Checked in registration order, after the domain sentinels
+	dbMappings = []StatusMapping{
+		{dbErrs.ErrUniqueViolation, http.StatusConflict},
+		{dbErrs.ErrForeignKeyViolation, http.StatusConflict},
+		{dbErrs.ErrExclusionViolation, http.StatusConflict},
+		{dbErrs.ErrCheckViolation, http.StatusBadRequest},
+		{dbErrs.ErrNotNullViolation, http.StatusBadRequest},
+		{dbErrs.ErrStringTooLong, http.StatusBadRequest},
+		{dbErrs.ErrInvalidText, http.StatusBadRequest},
+		{dbErrs.ErrNumericOutOfRange, http.StatusBadRequest},
+		{dbErrs.ErrSerializationFailure, http.StatusServiceUnavailable},
+		{dbErrs.ErrDeadlock, http.StatusServiceUnavailable},
+		{dbErrs.ErrLockNotAvailable, http.StatusServiceUnavailable},
+		{dbErrs.ErrReadOnly, http.StatusServiceUnavailable},
+	}
+	mappings []StatusMapping
+)
+
+// RegisterStatus maps a sentinel error to the status code of the responses it causes.
+// Registering a sentinel again replaces its status.
+func RegisterStatus(sentinel error, status int) {
+	mappingsMu.Lock()
+	defer mappingsMu.Unlock()
+
+	for i, m := range mappings {
+		if m.Err == sentinel {
+			mappings[i].Status = status
+			return
+		}
+	}
+	mappings = append(mappings, StatusMapping{sentinel, status})
+}
+
+// RegisterStatuses registers every mapping of ms in order. A sentinel wrapping another one must
+// come first, the first match wins.
+func RegisterStatuses(ms []StatusMapping) {
+	for _, m := range ms {
+		RegisterStatus(m.Err, m.Status)
+	}
+}
+
+// ErrorHandler writes the response of a request whose handler reported an error with ctx.Error.
+// Binding errors are 400, registered sentinels get their status, then the database error kinds,
+// and anything else is a 500 that does not leak its message.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		status, message := resolve(ctx.Errors.Last())
+		ctx.AbortWithStatusJSON(status, ErrorResponse{Message: message})
+	}
+}
+
+func resolve(err *gin.Error) (int, string) {
+	if err.IsType(gin.ErrorTypeBind) {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	mappingsMu.RLock()
+	defer mappingsMu.RUnlock()
+
+	for _, list := range [][]StatusMapping{mappings, dbMappings} {
+		for _, m := range list {
+			if errors.Is(err.Err, m.Err) {
+				return m.Status, m.Err.Error()
+			}
+		}
+	}
+
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	dbErrs "goroutines/pkg/database/errs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	errNotFound := errors.New("thing not found")
+	RegisterStatus(errNotFound, http.StatusNotFound)
+
+	cases := []struct {
+		name    string
+		err     func(ctx *gin.Context)
+		status  int
+		message string
+	}{
+		{"sentinel", func(ctx *gin.Context) { ctx.Error(errNotFound) }, http.StatusNotFound, "thing not found"},
+		{"bind", func(ctx *gin.Context) { ctx.Error(errors.New("bad json")).SetType(gin.ErrorTypeBind) }, http.StatusBadRequest, "bad json"},
+		{"db kind", func(ctx *gin.Context) {
+			ctx.Error(dbErrs.Classify(&pgconn.PgError{Code: "23505"}))
+		}, http.StatusConflict, "unique violation"},
+		{"unknown", func(ctx *gin.Context) { ctx.Error(errors.New("secret detail")) }, http.StatusInternalServerError, "Internal Server Error"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", tc.err)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.JSONEq(t, `{"message":"`+tc.message+`"}`, rec.Body.String())
+		})
+	}
+}
+
+func TestRegisterStatusReplaces(t *testing.T) {
+	errGone := errors.New("thing gone")
+	RegisterStatus(errGone, http.StatusNotFound)
+	registered := len(mappings)
+
+	// Building the routers again registers the same sentinels again
+	RegisterStatuses([]StatusMapping{{errGone, http.StatusGone}})
+	assert.Len(t, mappings, registered)
+
+	status, _ := resolve(&gin.Error{Err: errGone})
+	assert.Equal(t, http.StatusGone, status)
+}
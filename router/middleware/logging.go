@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"goroutines/pkg/logging"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID, accepted from the client and echoed in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs accepted from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+// RequestLog assigns the request ID, stores a logger carrying it in the request context and
+// writes one access log line per request. It must be registered before ErrorHandler to log the
+// final status, and after Tracing to attach the trace ID.
+func RequestLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.Must(uuid.NewV4()).String()
+		}
+		ctx.Header(RequestIDHeader, id)
+
+		logger := slog.Default().With(slog.String(logging.RequestIDKey, id))
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			logger = logger.With(slog.String("trace_id", span.TraceID().String()))
+		}
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), logger))
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", route),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if strategy := ctx.Writer.Header().Get(StrategyHeader); strategy != "" {
+			attrs = append(attrs, slog.String("strategy", strategy))
+		}
+		if err := ctx.Errors.Last(); err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(ctx.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII only, so clients can't forge log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
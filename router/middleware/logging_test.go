@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"goroutines/pkg/logging"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	router := gin.New()
+	router.Use(RequestLog(), ErrorHandler())
+	router.GET("/v1/product/:id", func(ctx *gin.Context) {
+		// Handlers and everything below log through the request logger
+		logging.FromContext(ctx.Request.Context()).Debug("handler")
+		ctx.Status(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"propagated", "req-42", "req-42"},
+		{"generated", "", ""},
+		{"control characters replaced", "forged\nlevel=ERROR", ""},
+		{"too long replaced", strings.Repeat("a", maxRequestIDLength+1), ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/v1/product/1", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, id)
+			} else {
+				assert.Len(t, id, 36, "generated uuid")
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+			var handler, access map[string]any
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &handler))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+
+			assert.Equal(t, id, handler[logging.RequestIDKey])
+			assert.Equal(t, id, access[logging.RequestIDKey])
+			assert.Equal(t, "Request", access["msg"])
+			assert.Equal(t, "/v1/product/:id", access["route"])
+			assert.EqualValues(t, http.StatusNoContent, access["status"])
+		})
+	}
+}
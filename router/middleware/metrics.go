@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"goroutines/pkg/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests matching no route, so scanners can't blow up the label cardinality
+const unmatchedRoute = "unmatched"
+
+// Metrics records every request once the handlers and the error middleware wrote the response,
+// so it must be registered before ErrorHandler
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveRequest(route, ctx.Request.Method, ctx.Writer.Status(), ctx.Writer.Header().Get(StrategyHeader), time.Since(start))
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"goroutines/pkg/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	errConflict := errors.New("already exists")
+	RegisterStatus(errConflict, http.StatusConflict)
+
+	stats := metrics.New(nil)
+	router := gin.New()
+	router.Use(Metrics(stats), ErrorHandler())
+	router.POST("/v1/product/:id", func(ctx *gin.Context) {
+		ctx.Header(StrategyHeader, "tx")
+		ctx.Error(errConflict)
+	})
+	router.GET("/metrics", gin.WrapH(stats.Handler()))
+
+	for _, path := range []string{"/v1/product/1", "/v1/product/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, _ := io.ReadAll(rec.Body)
+
+	// Labelled by route template and by the status written by ErrorHandler
+	assert.Contains(t, string(body), `goroutines_http_requests_total{method="POST",route="/v1/product/:id",status="409",strategy="tx"} 2`)
+	assert.Contains(t, string(body), `goroutines_http_requests_total{method="POST",route="unmatched",status="404",strategy=""} 1`)
+	assert.Contains(t, string(body), `goroutines_http_request_duration_seconds_bucket{method="POST",route="/v1/product/:id",status="409",strategy="tx",le="+Inf"} 2`)
+	assert.Contains(t, string(body), "go_goroutines ")
+	assert.Contains(t, string(body), "go_sched_latencies_seconds_bucket")
+	assert.Contains(t, string(body), "goroutines_service_goroutines_inflight ")
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// StorageHeader reports the storage backend serving the request
+const StorageHeader = "X-Storage"
+
+// Storage sets StorageHeader on every response, so clients can tell a mock server from a real one
+func Storage(name string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header(StorageHeader, name)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,5 @@
+package middleware
+
+// StrategyHeader selects the create strategy of a request and reports the one that served it.
+// Tracing, RequestLog and Metrics read it back from the response to label the request.
+const StrategyHeader = "X-Create-Strategy"
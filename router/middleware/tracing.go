@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"goroutines/pkg/tracing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing opens a server span per request, continuing the trace of an incoming traceparent header.
+// Handlers reach it through ctx.Request.Context(). It must be registered before ErrorHandler to see
+// the final status.
+func Tracing() gin.HandlerFunc {
+	tracer := tracing.Tracer()
+
+	return func(ctx *gin.Context) {
+		propagator := otel.GetTextMapPropagator()
+		parent := propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		spanCtx, span := tracer.Start(parent, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(ctx.Request.URL.Path),
+			))
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if strategy := ctx.Writer.Header().Get(StrategyHeader); strategy != "" {
+			span.SetAttributes(tracing.StrategyKey.String(strategy))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprint(status))
+		}
+	}
+}
@@ -2,13 +2,29 @@ package router
 
 import (
 	"context"
+	"goroutines/config"
+	"goroutines/internal/system/controller"
 	"goroutines/pkg/database"
+	"goroutines/pkg/health"
+	"goroutines/pkg/metrics"
 	v1 "goroutines/router/v1"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRouter(ctx context.Context, db *database.DB, router *gin.Engine) {
-	v1Route := v1.NewV1Router(ctx, db)
+func RegisterRouter(ctx context.Context, cfg *config.Container, db *database.DB, checks *health.Health, stats *metrics.Metrics, router *gin.Engine) error {
+	// Probes stay outside of the versioned API
+	probes := controller.NewHealthController(checks)
+	router.GET("/healthz", probes.Live)
+	router.GET("/readyz", probes.Ready)
+	router.GET("/metrics", gin.WrapH(stats.Handler()))
+	registerDebug(cfg.App, router)
+
+	v1Route, err := v1.NewV1Router(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
 	v1Route.Load(router)
+
+	return nil
 }
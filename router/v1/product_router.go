@@ -2,26 +2,78 @@ package v1
 
 import (
 	"context"
+	"goroutines/config"
+	categoryErrs "goroutines/internal/category/errs"
 	categoryRepository "goroutines/internal/category/repository"
+	"goroutines/internal/fixtures"
 	"goroutines/internal/product/controller"
+	"goroutines/internal/product/errs"
 	"goroutines/internal/product/repository"
 	"goroutines/internal/product/service"
 	"goroutines/pkg/database"
+	dbErrs "goroutines/pkg/database/errs"
+	"goroutines/pkg/database/memory"
+	"goroutines/router/middleware"
+	"net/http"
 )
 
+// productStatuses maps the product and category sentinels onto response status codes
+var productStatuses = []middleware.StatusMapping{
+	{Err: errs.ProductErrsCategoryNotFound, Status: http.StatusBadRequest},
+	{Err: errs.ProductErrsSkuOverflow, Status: http.StatusBadRequest},
+	{Err: errs.ProductErrsImageUrlInvalid, Status: http.StatusBadRequest},
+	{Err: errs.ProductErrsStrategyUnknown, Status: http.StatusBadRequest},
+	{Err: categoryErrs.CategoryErrsAlreadyExists, Status: http.StatusConflict},
+	{Err: errs.ProductErrsBatchDisabled, Status: http.StatusServiceUnavailable},
+}
+
 type ProductRouter struct {
 	Controller controller.ProductController
 }
 
-func NewProductRouter(ctx context.Context, db *database.DB) *ProductRouter {
-	productRepo := repository.NewProductRepository(db)
-	categoryRepo := categoryRepository.NewCategoryRepository(db)
+func NewProductRouter(ctx context.Context, cfg *config.Container, db *database.DB) (*ProductRouter, error) {
+	dbErrs.RegisterConstraints(categoryErrs.Constraints)
+	middleware.RegisterStatuses(productStatuses)
+
+	tx, dependency, err := newProductDependency(ctx, cfg, db)
+	if err != nil {
+		return nil, err
+	}
+	productService := service.NewProductService(tx, dependency, ctx)
+
+	strategies, err := service.NewStrategyRegistry(productService, cfg.App.CreateStrategy)
+	if err != nil {
+		return nil, err
+	}
 
-	productService := service.NewProductService(db, &service.ProductDependency{
-		Product:  productRepo,
-		Category: categoryRepo,
-	}, ctx)
 	return &ProductRouter{
-		Controller: controller.NewProductController(productService),
+		Controller: controller.NewProductController(strategies),
+	}, nil
+}
+
+// newProductDependency wires the Postgres repositories, or the in-memory ones filled with the
+// fixtures when running with the memory storage. The batched strategy needs Postgres.
+func newProductDependency(ctx context.Context, cfg *config.Container, db *database.DB) (database.TxManager, *service.ProductDependency, error) {
+	if cfg.App.Storage == config.StorageMemory {
+		products := repository.NewProductMemoryRepository()
+		categories := categoryRepository.NewCategoryMemoryRepository()
+		if err := fixtures.Load(ctx, cfg.App.FixturesFile, products, categories); err != nil {
+			return nil, nil, err
+		}
+
+		return memory.NewTxManager(), &service.ProductDependency{
+			Product:  products,
+			Category: categories,
+		}, nil
 	}
+
+	// Group commit writer, flushes every DefaultBatchMaxDelay or DefaultBatchMaxRows rows
+	batchWriter := repository.NewProductBatchWriter(db, repository.DefaultBatchMaxRows, repository.DefaultBatchMaxDelay)
+	batchWriter.Start(ctx)
+
+	return db, &service.ProductDependency{
+		Product:  repository.NewProductRepository(db),
+		Category: categoryRepository.NewCategoryRepository(db),
+		Batch:    batchWriter,
+	}, nil
 }
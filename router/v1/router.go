@@ -2,7 +2,9 @@ package v1
 
 import (
 	"context"
+	"goroutines/config"
 	"goroutines/pkg/database"
+	"goroutines/router/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,12 +15,23 @@ type V1Router interface {
 
 type v1Router struct {
 	Product *ProductRouter
+	System  *SystemRouter
+	// app decides how the admin endpoints are guarded
+	app *config.App
 }
 
-func NewV1Router(ctx context.Context, db *database.DB) *v1Router {
-	return &v1Router{
-		Product: NewProductRouter(ctx, db),
+func NewV1Router(ctx context.Context, cfg *config.Container, db *database.DB) (*v1Router, error) {
+	product, err := NewProductRouter(ctx, cfg, db)
+	if err != nil {
+		return nil, err
 	}
+
+	return &v1Router{
+		Product: product,
+		System:  NewSystemRouter(db),
+
+		app: cfg.App,
+	}, nil
 }
 
 func (v *v1Router) Load(router *gin.Engine) {
@@ -26,6 +39,14 @@ func (v *v1Router) Load(router *gin.Engine) {
 	{
 		// Product api endpoint
 		product := v1.Group("/product")
-		product.POST("/", v.Product.Controller.CreateProductGoroutines)
+		product.POST("/", v.Product.Controller.CreateProduct)
+
+		// Admin endpoints
+		if admin, ok := middleware.AdminGroup(v1, "/admin", v.app); ok {
+			admin.GET("/strategy", v.Product.Controller.GetStrategy)
+			admin.PUT("/strategy", v.Product.Controller.SetStrategy)
+			admin.GET("/pool", v.System.Controller.PoolStats)
+			admin.GET("/queries", v.System.Controller.TopQueries)
+		}
 	}
 }
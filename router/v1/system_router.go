@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"goroutines/internal/system/controller"
+	"goroutines/internal/system/errs"
+	"goroutines/pkg/database"
+	"goroutines/router/middleware"
+	"net/http"
+)
+
+// systemStatuses maps the system sentinels onto response status codes
+var systemStatuses = []middleware.StatusMapping{
+	{Err: errs.SystemErrsInvalidLimit, Status: http.StatusBadRequest},
+	{Err: errs.SystemErrsInvalidSort, Status: http.StatusBadRequest},
+	{Err: errs.SystemErrsInvalidTrace, Status: http.StatusBadRequest},
+	{Err: errs.SystemErrsTraceRunning, Status: http.StatusConflict},
+	{Err: errs.SystemErrsNoDatabase, Status: http.StatusNotImplemented},
+}
+
+type SystemRouter struct {
+	Controller controller.SystemController
+}
+
+func NewSystemRouter(db *database.DB) *SystemRouter {
+	middleware.RegisterStatuses(systemStatuses)
+
+	return &SystemRouter{
+		Controller: controller.NewSystemController(db),
+	}
+}
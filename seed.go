@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"goroutines/config"
+	"goroutines/internal/product/seed"
+	"goroutines/pkg/database"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// progressPeriod throttles the seed progress line
+const progressPeriod = 500 * time.Millisecond
+
+// runSeed runs the seed subcommand and returns the process exit code
+func runSeed(ctx context.Context, cfg *config.Container, args []string) int {
+	opts := seed.Options{}
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.IntVar(&opts.Count, "count", 10000, "number of products to generate")
+	fs.Uint64Var(&opts.Seed, "seed", 1, "seed of the generated data, the same seed gives the same products")
+	fs.IntVar(&opts.Workers, "workers", runtime.NumCPU(), "goroutines writing batches concurrently")
+	fs.IntVar(&opts.BatchSize, "batch", 1000, "products written per transaction")
+	fs.BoolVar(&opts.Truncate, "truncate", false, "empty the products table first")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := database.New(ctx, cfg.DB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	start := time.Now()
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+	opts.Progress = func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done < total && time.Since(last) < progressPeriod {
+			return
+		}
+		last = time.Now()
+
+		elapsed := time.Since(start)
+		fmt.Fprintf(os.Stderr, "\rSeeded %d/%d products (%.0f%%), %.0f rows/s",
+			done, total, float64(done)*100/float64(total), float64(done)/elapsed.Seconds())
+	}
+
+	err = seed.Run(ctx, db, opts)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Seed failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Seeded %d products with seed %d in %s\n", opts.Count, opts.Seed, time.Since(start).Round(time.Millisecond))
+	return 0
+}
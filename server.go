@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goroutines/config"
+	"goroutines/pkg/database"
+	"goroutines/pkg/health"
+	"goroutines/pkg/ipapi"
+	"goroutines/pkg/metrics"
+	"goroutines/router/middleware"
+	"goroutines/util"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serve runs server on listener until ctx is done, then shuts it down gracefully: readiness starts
+// failing and requests are still served for drain, then within grace new connections are refused,
+// in-flight requests finish, cancelRoot stops the background work and the goroutines started
+// through util are waited for. The caller closes the pools after.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, drain, grace time.Duration, checks *health.Health, cancelRoot context.CancelFunc) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// The server stopped on its own, nothing is left to drain but the background work
+		cancelRoot()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down", slog.Duration("drain", drain), slog.Duration("grace", grace))
+	checks.Shutdown()
+	// Load balancers only stop routing here once they probed the failing readiness
+	drainTimer := time.NewTimer(drain)
+	select {
+	case <-drainTimer.C:
+	case err := <-serveErr:
+		drainTimer.Stop()
+		serveErr <- err
+	}
+
+	graceCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(graceCtx); err != nil {
+		errs = append(errs, fmt.Errorf("http shutdown: %w", err))
+	}
+
+	cancelRoot()
+	if err := util.Wait(graceCtx); err != nil {
+		errs = append(errs, fmt.Errorf("drain goroutines: %w", err))
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// newRouter chains the middlewares. Tracing, logging and metrics wrap the panic recovery, so the
+// 500 of a recovered panic still ends its span, writes its access log line and records its sample.
+func newRouter(cfg *config.Container, stats *metrics.Metrics) *gin.Engine {
+	router := gin.New()
+	router.Use(
+		middleware.Tracing(),
+		middleware.RequestLog(),
+		middleware.Metrics(stats),
+		gin.Recovery(),
+		middleware.Storage(cfg.App.Storage),
+		middleware.ErrorHandler(),
+	)
+
+	return router
+}
+
+// newHealth assembles the readiness checks of the configured dependencies, db is nil with the memory storage
+func newHealth(cfg *config.Container, db *database.DB) *health.Health {
+	checks := health.New(cfg.Health.CheckTimeout)
+
+	if db != nil {
+		checks.Add(
+			health.Check{Name: "database", Fn: health.Ping(db)},
+			health.Check{Name: "pool", Fn: health.PoolSaturation(db, cfg.Health.PoolSaturation)},
+			// A read only server knows its schema differs and serves anyway
+			health.Check{Name: "schema", Fn: health.Schema(db), Optional: db.ReadOnly()},
+		)
+	}
+	if cfg.Health.CheckIpapi {
+		checks.Add(health.Check{Name: "ipapi", Fn: health.HTTP(http.DefaultClient, ipapi.BaseURL), Optional: true})
+	}
+
+	return checks
+}
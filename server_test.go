@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goroutines/config"
+	"goroutines/internal/system/controller"
+	"goroutines/pkg/database"
+	"goroutines/pkg/health"
+	"goroutines/pkg/metrics"
+	routes "goroutines/router"
+	"goroutines/router/middleware"
+	"goroutines/util"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const productBody = `{"name":"Classic T-Shirt","sku":"SKU-1","category":"Clothing","imageUrl":"https://example.com/t.jpg",` +
+	`"notes":"Best seller","price":100,"stock":10,"location":"Jakarta","isAvailable":true}`
+
+func TestGracefulShutdownDrainsRequests(t *testing.T) {
+	cfg := &config.Container{
+		App: &config.App{Storage: config.StorageMemory, CreateStrategy: "goroutines"},
+		DB:  &config.DB{},
+		Log: &config.Log{},
+	}
+
+	testGracefulShutdown(t, cfg)
+}
+
+// Needs a migrated Postgres, e.g.:
+//
+//	DB_HOST=localhost DB_PORT=5432 DB_USERNAME=postgres DB_PASSWORD=postgres DB_NAME=goroutines \
+//	DB_PARAMS=sslmode=disable go test -run TestGracefulShutdownPostgres .
+func TestGracefulShutdownPostgres(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST not set, skipping database shutdown test")
+	}
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	cfg.App.Storage = config.StoragePostgres
+	// The batch writer holds rows of in-flight requests when the shutdown starts
+	cfg.App.CreateStrategy = "batched"
+
+	testGracefulShutdown(t, cfg)
+}
+
+// testGracefulShutdown shuts the server down while n slow requests are in flight: every one
+// of them must succeed, new connections must be refused, the pool must close and nothing may
+// be left running
+func testGracefulShutdown(t *testing.T, cfg *config.Container) {
+	gin.SetMode(gin.TestMode)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Like main, the pool and its monitors live on the root context
+	var db *database.DB
+	if cfg.App.Storage == config.StoragePostgres {
+		var err error
+		db, err = database.New(ctx, cfg.DB)
+		require.NoError(t, err)
+	}
+
+	const n = 50
+	// Slow handlers, so requests are still in flight when the shutdown starts. Late requests
+	// that still get through are not counted.
+	arrived := make(chan struct{}, n)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		time.Sleep(300 * time.Millisecond)
+		c.Next()
+	}, middleware.ErrorHandler())
+	checks := health.New(time.Second)
+	require.NoError(t, routes.RegisterRouter(ctx, cfg, db, checks, metrics.New(db), router))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := fmt.Sprintf("http://%s/v1/product/", listener.Addr())
+
+	stopCtx, stop := context.WithCancel(ctx)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(stopCtx, &http.Server{Handler: router}, listener, 0, 5*time.Second, checks, cancel)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	post := func() error {
+		res, err := client.Post(url, "application/json", strings.NewReader(productBody))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		io.Copy(io.Discard, res.Body)
+
+		if res.StatusCode != http.StatusCreated {
+			return fmt.Errorf("unexpected status %d", res.StatusCode)
+		}
+		return nil
+	}
+
+	results := make(chan error, n)
+	for range n {
+		go func() { results <- post() }()
+	}
+
+	// Shut down only once every request reached the slow handler
+	deadline := time.After(10 * time.Second)
+	for range n {
+		select {
+		case <-arrived:
+		case <-deadline:
+			t.Fatal("requests never reached the handler")
+		}
+	}
+	stop()
+
+	// Once shutting down, new connections are refused rather than accepted and dropped
+	assert.Eventually(t, func() bool {
+		var opErr *net.OpError
+		return errors.As(post(), &opErr) && opErr.Op == "dial"
+	}, 5*time.Second, 10*time.Millisecond, "late requests are still accepted")
+
+	for range n {
+		assert.NoError(t, <-results, "in-flight request dropped")
+	}
+	require.NoError(t, <-served)
+
+	if db != nil {
+		// Closing the pool waits for every acquired connection, a leaked one hangs here
+		closed := make(chan struct{})
+		go func() {
+			db.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("pool close hangs on connections still acquired")
+		}
+	}
+
+	assert.Error(t, ctx.Err(), "root context canceled")
+	assert.False(t, checks.Ready(context.Background()).Ready(), "readiness fails once shutting down")
+	assert.Zero(t, util.Running(), "goroutines left behind")
+}
+
+func TestReadinessFailsDuringDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	router := gin.New()
+	checks := health.New(time.Second)
+	probes := controller.NewHealthController(checks)
+	router.GET("/readyz", probes.Ready)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := fmt.Sprintf("http://%s/readyz", listener.Addr())
+
+	const drain = 500 * time.Millisecond
+	stopCtx, stop := context.WithCancel(ctx)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(stopCtx, &http.Server{Handler: router}, listener, drain, 5*time.Second, checks, cancel)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	ready := func() int {
+		res, err := client.Get(url)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		io.Copy(io.Discard, res.Body)
+		return res.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, ready())
+
+	stopped := time.Now()
+	stop()
+
+	// Still listening during the drain delay, with a failing readiness
+	assert.Eventually(t, func() bool { return ready() == http.StatusServiceUnavailable }, drain/2, 10*time.Millisecond)
+	require.NoError(t, <-served)
+	assert.GreaterOrEqual(t, time.Since(stopped), drain, "shut down before the drain delay")
+}
+
+func TestRecoveredPanicIsObserved(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	// The recovery middleware prints the stack there
+	gin.DefaultErrorWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultErrorWriter = os.Stderr })
+
+	stats := metrics.New(nil)
+	router := newRouter(&config.Container{App: &config.App{Storage: config.StorageMemory}}, stats)
+	router.GET("/panic", func(*gin.Context) { panic("handler bug") })
+	router.GET("/metrics", gin.WrapH(stats.Handler()))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code, "span status")
+
+	var access map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &access), "one access log line")
+	assert.EqualValues(t, http.StatusInternalServerError, access["status"])
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `goroutines_http_requests_total{method="GET",route="/panic",status="500",strategy=""} 1`)
+}
@@ -0,0 +1,246 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"goroutines/util"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func double(_ context.Context, n int) (int, error) {
+	return n * 2, nil
+}
+
+func TestMapKeepsOrderAndLimit(t *testing.T) {
+	var inFlight, peak int32
+	results := Map(context.Background(), []int{1, 2, 3, 4, 5, 6}, 2, func(ctx context.Context, n int) (int, error) {
+		cur := atomic.AddInt32(&inFlight, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if cur <= old || atomic.CompareAndSwapInt32(&peak, old, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+
+		if n == 3 {
+			return 0, errors.New("three")
+		}
+		return n * 2, nil
+	})
+
+	assert.LessOrEqual(t, peak, int32(2))
+	assert.Equal(t, util.Result[int]{Result: 2}, results[0])
+	assert.EqualError(t, results[2].Error, "three")
+	assert.Equal(t, util.Result[int]{Result: 12}, results[5])
+}
+
+func TestMapRecoversPanic(t *testing.T) {
+	results := Map(context.Background(), []int{1}, 1, func(ctx context.Context, n int) (int, error) {
+		panic("boom")
+	})
+
+	assert.ErrorIs(t, results[0].Error, util.ErrPanic)
+}
+
+func TestMapCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := Map(ctx, []int{1, 2, 3}, 1, double)
+	assert.ErrorIs(t, results[2].Error, context.Canceled)
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	ctx := context.Background()
+
+	outs := FanOut(ctx, Generate(ctx, 1, 2, 3, 4), 3, double)
+	assert.Len(t, outs, 3)
+
+	var got []int
+	for r := range FanIn(ctx, outs...) {
+		assert.NoError(t, r.Error)
+		got = append(got, r.Result)
+	}
+	sort.Ints(got)
+	assert.Equal(t, []int{2, 4, 6, 8}, got)
+}
+
+func TestPipelineUnordered(t *testing.T) {
+	ctx := context.Background()
+
+	sum := 0
+	for r := range Pipeline(ctx, Generate(ctx, 1, 2, 3), 2, double) {
+		sum += r.Result
+	}
+	assert.Equal(t, 12, sum)
+}
+
+func TestOrderedPipeline(t *testing.T) {
+	ctx := context.Background()
+
+	// Earlier items are slower, so only the ordering logic keeps them first
+	slowFirst := func(ctx context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(5-n) * 5 * time.Millisecond)
+		return n, nil
+	}
+
+	var got []int
+	for r := range OrderedPipeline(ctx, Generate(ctx, 1, 2, 3, 4), 4, slowFirst) {
+		got = append(got, r.Result)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, got)
+}
+
+func TestPipelineStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	in := make(chan int)
+	out := Pipeline(ctx, in, 2, double)
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("pipeline did not stop after cancel")
+	}
+}
+
+func TestFirstOf(t *testing.T) {
+	ctx := context.Background()
+
+	r := FirstOf(ctx,
+		func(ctx context.Context) (string, error) {
+			<-ctx.Done() // losers are canceled
+			return "slow", ctx.Err()
+		},
+		func(ctx context.Context) (string, error) {
+			return "fast", nil
+		},
+	)
+	assert.Equal(t, util.Result[string]{Result: "fast"}, r)
+
+	r = FirstOf(ctx,
+		func(ctx context.Context) (string, error) { return "", errors.New("a") },
+		func(ctx context.Context) (string, error) { return "", errors.New("b") },
+	)
+	assert.ErrorContains(t, r.Error, "a")
+	assert.ErrorContains(t, r.Error, "b")
+}
+
+func TestOrderedPipelineStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	blocked := func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}
+
+	out := OrderedPipeline(ctx, Generate(ctx, 1, 2, 3), 2, blocked)
+	cancel()
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("ordered pipeline did not stop after cancel")
+	}
+}
+
+func TestFirstOfCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := FirstOf(ctx, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.ErrorIs(t, r.Error, context.Canceled)
+}
+
+func TestSingleflight(t *testing.T) {
+	var sf Singleflight[string, int]
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]util.Result[int], 5)
+	shared := make([]bool, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], shared[i] = sf.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				close(started)
+				<-release
+				return 42, nil
+			})
+		}(i)
+	}
+
+	// The call runs, then every other caller joins it before it is released
+	<-started
+	assert.Eventually(t, func() bool {
+		sf.mu.Lock()
+		defer sf.mu.Unlock()
+		f, ok := sf.calls["key"]
+		return ok && f.dups == len(results)-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	for i, r := range results {
+		assert.Equal(t, util.Result[int]{Result: 42}, r)
+		assert.True(t, shared[i])
+	}
+}
+
+func TestSingleflightCallerCancel(t *testing.T) {
+	var sf Singleflight[string, int]
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, _ := sf.Do(ctx, "key", func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	})
+	assert.ErrorIs(t, r.Error, context.Canceled)
+}
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.Background()
+	sem := NewSemaphore(3)
+
+	assert.NoError(t, sem.Acquire(ctx, 2))
+	assert.False(t, sem.TryAcquire(2))
+	assert.True(t, sem.TryAcquire(1))
+	assert.ErrorIs(t, sem.Acquire(ctx, 4), ErrWeightTooLarge)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sem.Acquire(timeout, 1), context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = sem.Acquire(ctx, 3)
+		close(acquired)
+	}()
+
+	sem.Release(2)
+	sem.Release(1)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not woken up")
+	}
+}
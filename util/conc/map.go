@@ -0,0 +1,61 @@
+// Package conc holds the generic concurrency helpers shared by the services.
+//
+// Every helper takes a context, stops early when it is canceled and reports
+// per-item outcomes as util.Result. Panics inside user functions are recovered
+// and surfaced as *util.PanicError.
+package conc
+
+import (
+	"context"
+	"goroutines/util"
+	"sync"
+)
+
+// Map runs fn over every item with at most limit calls in flight and returns the
+// results in input order. A limit <= 0 means one goroutine per item.
+//
+// Items that were not started because ctx was canceled carry ctx.Err().
+func Map[T, R interface{}](ctx context.Context, items []T, limit int, fn func(ctx context.Context, item T) (R, error)) []util.Result[R] {
+	if limit <= 0 || limit > len(items) {
+		limit = len(items)
+	}
+
+	results := make([]util.Result[R], len(items))
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(items); j++ {
+				results[j] = util.Result[R]{Error: ctx.Err()}
+			}
+			wg.Wait()
+			return results
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = call(ctx, item, fn)
+		}(i, item)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// call runs fn and turns a panic into the result error
+func call[T, R interface{}](ctx context.Context, item T, fn func(ctx context.Context, item T) (R, error)) (r util.Result[R]) {
+	defer func() {
+		if err := util.Recover(recover()); err != nil {
+			r = util.Result[R]{Error: err}
+		}
+	}()
+
+	r.Result, r.Error = fn(ctx, item)
+	return r
+}
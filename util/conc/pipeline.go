@@ -0,0 +1,163 @@
+package conc
+
+import (
+	"context"
+	"goroutines/util"
+	"sync"
+)
+
+// Generate sends items on the returned channel until they run out or ctx is canceled
+func Generate[T interface{}](ctx context.Context, items ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		for _, item := range items {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// FanOut starts workers goroutines reading from in. Each worker has its own output
+// channel, closed once in is drained or ctx is canceled.
+func FanOut[T, R interface{}](ctx context.Context, in <-chan T, workers int, fn func(ctx context.Context, item T) (R, error)) []<-chan util.Result[R] {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	outs := make([]<-chan util.Result[R], workers)
+	for i := 0; i < workers; i++ {
+		out := make(chan util.Result[R])
+		outs[i] = out
+
+		go func() {
+			defer close(out)
+
+			for item := range recv(ctx, in) {
+				if !send(ctx, out, call(ctx, item, fn)) {
+					return
+				}
+			}
+		}()
+	}
+
+	return outs
+}
+
+// FanIn merges chans into one channel, closed once every input is closed or ctx is canceled
+func FanIn[T interface{}](ctx context.Context, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chans))
+	for _, ch := range chans {
+		go func(ch <-chan T) {
+			defer wg.Done()
+
+			for v := range recv(ctx, ch) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Pipeline applies fn to every item of in with workers goroutines. Results are emitted
+// as soon as they are ready, so their order is not related to the input order.
+func Pipeline[T, R interface{}](ctx context.Context, in <-chan T, workers int, fn func(ctx context.Context, item T) (R, error)) <-chan util.Result[R] {
+	return FanIn(ctx, FanOut(ctx, in, workers, fn)...)
+}
+
+// OrderedPipeline is Pipeline that emits results in input order. At most workers
+// items are processed ahead of the oldest result not yet received.
+func OrderedPipeline[T, R interface{}](ctx context.Context, in <-chan T, workers int, fn func(ctx context.Context, item T) (R, error)) <-chan util.Result[R] {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// Every item gets its own future; the queue keeps them in arrival order and its
+	// capacity bounds how far the workers can run ahead.
+	futures := make(chan chan util.Result[R], workers)
+	go func() {
+		defer close(futures)
+
+		for item := range recv(ctx, in) {
+			future := make(chan util.Result[R], 1)
+			if !send(ctx, futures, future) {
+				return
+			}
+
+			go func(item T) {
+				future <- call(ctx, item, fn)
+			}(item)
+		}
+	}()
+
+	out := make(chan util.Result[R])
+	go func() {
+		defer close(out)
+
+		for future := range futures {
+			var r util.Result[R]
+			select {
+			case r = <-future:
+			case <-ctx.Done():
+				return
+			}
+
+			if !send(ctx, out, r) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// recv forwards in until it is closed or ctx is canceled
+func recv[T interface{}](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !send(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// send reports whether v was delivered before ctx was canceled
+func send[T interface{}](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
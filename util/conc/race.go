@@ -0,0 +1,44 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"goroutines/util"
+)
+
+// FirstOf runs every fn concurrently and returns the first successful result.
+// The context passed to the others is canceled as soon as one succeeds.
+// When all of them fail the errors are joined.
+func FirstOf[T interface{}](ctx context.Context, fns ...func(ctx context.Context) (T, error)) util.Result[T] {
+	if len(fns) == 0 {
+		return util.Result[T]{Error: errors.New("conc: FirstOf called without functions")}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered for every racer, so the losers finish without a receiver
+	results := make(chan util.Result[T], len(fns))
+	for _, fn := range fns {
+		go func(fn func(ctx context.Context) (T, error)) {
+			results <- call(ctx, struct{}{}, func(ctx context.Context, _ struct{}) (T, error) {
+				return fn(ctx)
+			})
+		}(fn)
+	}
+
+	errs := make([]error, 0, len(fns))
+	for range fns {
+		select {
+		case r := <-results:
+			if r.Error == nil {
+				return r
+			}
+			errs = append(errs, r.Error)
+		case <-ctx.Done():
+			return util.Result[T]{Error: ctx.Err()}
+		}
+	}
+
+	return util.Result[T]{Error: errors.Join(errs...)}
+}
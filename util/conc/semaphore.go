@@ -0,0 +1,112 @@
+package conc
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrWeightTooLarge = errors.New("conc: semaphore weight exceeds its size")
+
+// Semaphore is a weighted semaphore. Waiters are served in FIFO order, so a large
+// request is not starved by a stream of small ones.
+type Semaphore struct {
+	size    int64
+	cur     int64
+	mu      sync.Mutex
+	waiters list.List
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Acquire blocks until n units are available or ctx is canceled
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrWeightTooLarge
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted while we were giving up, hand the units back
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			front := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// The head leaving may unblock the ones behind it
+			if front && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n units without blocking and reports whether it succeeded
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+
+	return false
+}
+
+// Release gives back n units
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("conc: semaphore released more than held")
+	}
+	s.notifyWaiters()
+}
+
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
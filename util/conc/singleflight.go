@@ -0,0 +1,72 @@
+package conc
+
+import (
+	"context"
+	"goroutines/util"
+	"sync"
+)
+
+// Singleflight collapses concurrent calls sharing a key into one execution
+type Singleflight[K comparable, V interface{}] struct {
+	mu    sync.Mutex
+	calls map[K]*flight[V]
+}
+
+type flight[V interface{}] struct {
+	done   chan struct{}
+	result util.Result[V]
+	dups   int
+}
+
+// Do runs fn once per key at a time; callers arriving while it runs wait for the same result.
+// fn gets a context detached from any single caller, so one caller giving up does not
+// cancel the others. A caller whose own ctx is canceled returns ctx.Err() right away.
+// shared reports whether the result was handed to more than one caller.
+func (s *Singleflight[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (result util.Result[V], shared bool) {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = make(map[K]*flight[V])
+	}
+
+	f, ok := s.calls[key]
+	if ok {
+		f.dups++
+	} else {
+		f = &flight[V]{done: make(chan struct{})}
+		s.calls[key] = f
+
+		go func() {
+			f.result = call(context.WithoutCancel(ctx), struct{}{}, func(ctx context.Context, _ struct{}) (V, error) {
+				return fn(ctx)
+			})
+
+			s.mu.Lock()
+			if s.calls[key] == f {
+				delete(s.calls, key)
+			}
+			s.mu.Unlock()
+
+			close(f.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		s.mu.Lock()
+		shared = f.dups > 0
+		s.mu.Unlock()
+
+		return f.result, shared
+	case <-ctx.Done():
+		return util.Result[V]{Error: ctx.Err()}, false
+	}
+}
+
+// Forget drops key so the next Do starts a new execution instead of joining the running one
+func (s *Singleflight[K, V]) Forget(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.calls, key)
+}
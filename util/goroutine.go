@@ -0,0 +1,150 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrPanic is matched by errors.Is for every error produced from a recovered panic
+var ErrPanic = errors.New("goroutine panicked")
+
+// PanicError carries the recovered value and the stack of the goroutine that panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrPanic, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrPanic
+}
+
+// Goroutines started by Go, GoInto and GoSafe are counted, so shutdown can wait for them
+var (
+	runningMu sync.Mutex
+	running   int
+	// idle is closed whenever running drops to zero
+	idle = closedChan()
+)
+
+func closedChan() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+func track() {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	if running == 0 {
+		idle = make(chan struct{})
+	}
+	running++
+}
+
+func untrack() {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	running--
+	if running == 0 {
+		close(idle)
+	}
+}
+
+// Running returns the number of goroutines started by Go, GoInto and GoSafe that did not return yet
+func Running() int {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	return running
+}
+
+// Wait blocks until every goroutine started by Go, GoInto and GoSafe returned, or ctx is done.
+// Goroutines started while some are still running are waited for as well.
+func Wait(ctx context.Context) error {
+	runningMu.Lock()
+	ch := idle
+	runningMu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d goroutines still running: %w", Running(), ctx.Err())
+	}
+}
+
+// Go runs fn on a new goroutine and delivers its outcome on a channel with room for it,
+// so the goroutine never blocks on a caller that stopped listening.
+func Go[T interface{}](fn func() (T, error)) <-chan Result[T] {
+	result := make(chan Result[T], 1)
+	GoInto(result, fn)
+
+	return result
+}
+
+// GoInto runs fn on a new goroutine, sends its outcome on result and closes it.
+// A panic in fn is recovered, logged and sent as a *PanicError instead of crashing the process.
+func GoInto[T interface{}](result chan<- Result[T], fn func() (T, error)) {
+	track()
+	go func() {
+		defer untrack()
+		defer close(result)
+
+		var r Result[T]
+		func() {
+			defer func() {
+				if err := Recover(recover()); err != nil {
+					r = Result[T]{Error: err}
+				}
+			}()
+
+			r.Result, r.Error = fn()
+		}()
+
+		result <- r
+	}()
+}
+
+// GoSafe runs fn on a new goroutine for background work that has nobody to report to.
+// A panic is recovered and logged under name.
+func GoSafe(name string, fn func()) {
+	track()
+	go func() {
+		defer untrack()
+		defer func() {
+			if err := Recover(recover()); err != nil {
+				slog.Error("background goroutine stopped", slog.String("name", name))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// Recover turns the value of recover() into an error, logging the stack. It must be called
+// directly from a deferred function: defer func() { err = util.Recover(recover()) }()
+func Recover(r interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	err := &PanicError{
+		Value: r,
+		Stack: debug.Stack(),
+	}
+	slog.Error("recovered panic",
+		slog.Any("panic", r),
+		slog.String("stack", string(err.Stack)))
+
+	return err
+}
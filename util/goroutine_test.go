@@ -0,0 +1,53 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoRecoversPanic(t *testing.T) {
+	result := <-Go(func() (int, error) {
+		panic("boom")
+	})
+
+	var panicErr *PanicError
+	if assert.ErrorAs(t, result.Error, &panicErr) {
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.NotEmpty(t, panicErr.Stack)
+	}
+	assert.True(t, errors.Is(result.Error, ErrPanic))
+}
+
+func TestGoIntoClosesChannel(t *testing.T) {
+	ch := make(chan Result[int])
+	GoInto(ch, func() (int, error) {
+		return 1, nil
+	})
+
+	assert.Equal(t, Result[int]{Result: 1}, <-ch)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestWaitTracksGoroutines(t *testing.T) {
+	release := make(chan struct{})
+	GoSafe("blocked", func() { <-release })
+	result := Go(func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 2, Running())
+
+	close(release)
+	assert.NoError(t, Wait(context.Background()))
+	assert.Zero(t, Running())
+	assert.Equal(t, 1, (<-result).Result)
+}